@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// anonymizedKeys are blanked by --anonymize.
+var anonymizedKeys = map[string]bool{"email": true, "id": true}
+
+// defaultRedactKeys are blanked by --redact unless redact_keys is set.
+var defaultRedactKeys = []string{"email", "id", "timestamp"}
+
+var (
+	dateRe      = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	timestampRe = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})T(.*)$`)
+)
+
+// anonDay replaces a date with its position relative to base: base is
+// "Day 1", the day after "Day 2", the day before "Day 0".
+func anonDay(base, date string) string {
+	b, err1 := time.Parse("2006-01-02", base)
+	d, err2 := time.Parse("2006-01-02", date)
+	if err1 != nil || err2 != nil {
+		return date
+	}
+	return fmt.Sprintf("Day %d", int(d.Sub(b).Hours()/24)+1)
+}
+
+// anonymizeJSON rewrites an API response for --anonymize: identifying
+// keys are blanked, dates become relative days and timestamps keep only
+// their time. A body that isn't JSON is returned unchanged.
+func anonymizeJSON(body []byte, base string) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+	out, err := json.Marshal(anonymizeValue(v, base))
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+func anonymizeValue(v any, base string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if anonymizedKeys[k] {
+				v[k] = ""
+				continue
+			}
+			v[k] = anonymizeValue(val, base)
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = anonymizeValue(val, base)
+		}
+	case string:
+		if dateRe.MatchString(v) {
+			return anonDay(base, v)
+		}
+		if m := timestampRe.FindStringSubmatch(v); m != nil {
+			return anonDay(base, m[1]) + " " + m[2]
+		}
+	}
+	return v
+}
+
+// redactJSON blanks the values of redact_keys (or defaultRedactKeys),
+// at any depth, in an API response for --redact. Unlike --anonymize it
+// leaves everything else as the API sent it. A body that isn't JSON is
+// returned unchanged.
+func redactJSON(body []byte) []byte {
+	keys := config.RedactKeys
+	if len(keys) == 0 {
+		keys = defaultRedactKeys
+	}
+	redacted := map[string]bool{}
+	for _, k := range keys {
+		redacted[k] = true
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+	out, err := json.Marshal(redactValue(v, redacted))
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+func redactValue(v any, redacted map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if redacted[k] {
+				v[k] = ""
+				continue
+			}
+			v[k] = redactValue(val, redacted)
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = redactValue(val, redacted)
+		}
+	}
+	return v
+}
@@ -0,0 +1,627 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+}
+
+type StoredToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Client talks to the Oura API and manages the stored OAuth token.
+type Client struct {
+	HTTP      *http.Client
+	APIBase   string
+	TokenURL  string
+	TokenPath string
+	Config    Config
+
+	// CacheDir, if set, stores every successful response. Offline
+	// serves responses only from there, without touching the network.
+	CacheDir string
+	Offline  bool
+	cacheMu  sync.Mutex // keeps a cached body and its ETag in step
+
+	// UserAgent is sent with every request.
+	UserAgent string
+
+	// MaxRetries is how often a request failing with a transient
+	// network error is retried.
+	MaxRetries int
+
+	// Limiter, if set, paces every request sent to the API, retries
+	// included, however many goroutines share the client.
+	Limiter *rate.Limiter
+
+	// RecordTimings makes Get note how long each call took, in Timings.
+	RecordTimings bool
+	Timings       []Timing
+	timingsMu     sync.Mutex
+
+	// tokenMu guards token and is held across a refresh, so requests
+	// running concurrently refresh it once between them.
+	tokenMu sync.Mutex
+	token   *StoredToken
+}
+
+// Timing is how long one Get took, retries and token refresh included.
+type Timing struct {
+	Endpoint string
+	Duration time.Duration
+}
+
+// APIError is a non-200 response from the API.
+type APIError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// OAuthError is an error response from the token endpoint.
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+	Body        []byte `json:"-"`
+}
+
+// Error gives guidance for the common failures instead of the raw body.
+func (e *OAuthError) Error() string {
+	switch e.Code {
+	case "invalid_grant":
+		return "your authorization expired, run 'oura auth' again"
+	case "invalid_client":
+		return fmt.Sprintf("the app was rejected, check client_id and client_secret in %s", getConfigPath())
+	case "":
+		return string(e.Body)
+	}
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	}
+	return e.Code
+}
+
+// isScopeError reports whether err is a 403, which the API returns when
+// the token lacks the endpoint's scope.
+func isScopeError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// ErrNotCached is returned in offline mode for requests never made online.
+var ErrNotCached = errors.New("not cached — run online first")
+
+// NewClient returns a client using the current endpoints and the active
+// profile's token file and cache.
+func NewClient(cfg Config) *Client {
+	return &Client{
+		HTTP:      newHTTPClient(cfg.PinSHA256),
+		APIBase:   apiBase,
+		TokenURL:  tokenURL,
+		TokenPath: getTokenPath(),
+		Config:    cfg,
+		CacheDir:  getCacheDir(),
+		UserAgent: userAgent(cfg),
+
+		MaxRetries: *maxRetriesFlag,
+		Limiter:    newLimiter(*qpsFlag),
+	}
+}
+
+// newLimiter returns a limiter allowing qps requests a second, or nil
+// (no limit) if qps isn't positive.
+func newLimiter(qps float64) *rate.Limiter {
+	if qps <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(qps), 1)
+}
+
+// userAgent returns --user-agent, the config's user_agent, or
+// oura-cli/VERSION.
+func userAgent(cfg Config) string {
+	switch {
+	case *uaFlag != "":
+		return *uaFlag
+	case cfg.UserAgent != "":
+		return cfg.UserAgent
+	}
+	return "oura-cli/" + version
+}
+
+// newRequest builds a request with the headers every call carries.
+func (c *Client) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+	return req, nil
+}
+
+// newHTTPClient returns an HTTP client that uses --proxy if set, and
+// otherwise HTTP_PROXY/HTTPS_PROXY/NO_PROXY from the environment. With
+// pins, TLS connections must also present one of those public keys.
+//
+// The transport requests gzip and decompresses it transparently, which
+// matters for heart rate ranges. Requests that set Accept-Encoding get
+// the compressed body back; Get decompresses those with readBody.
+func newHTTPClient(pins []string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxy, err := url.Parse(*proxyFlag); err == nil && *proxyFlag != "" {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if len(pins) > 0 {
+		transport.TLSClientConfig = &tls.Config{VerifyPeerCertificate: verifyPins(pins)}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// ErrPinMismatch is returned when no certificate the server presented
+// has a pinned public key.
+var ErrPinMismatch = errors.New("server certificate doesn't match pin_sha256 in config; the connection may be intercepted, so the request was aborted")
+
+// verifyPins checks, after the usual chain verification, that some
+// certificate in the verified chain has a public key whose SHA-256 (of
+// its SubjectPublicKeyInfo, base64) is pinned.
+func verifyPins(pins []string) func([][]byte, [][]*x509.Certificate) error {
+	return func(_ [][]byte, chains [][]*x509.Certificate) error {
+		for _, chain := range chains {
+			for _, cert := range chain {
+				sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				if slices.Contains(pins, base64.StdEncoding.EncodeToString(sum[:])) {
+					return nil
+				}
+			}
+		}
+		return ErrPinMismatch
+	}
+}
+
+// client is the CLI's API client, created in main once config is loaded.
+var client *Client
+
+func (c *Client) SaveToken(token *StoredToken) error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.saveToken(token)
+}
+
+func (c *Client) saveToken(token *StoredToken) error {
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.TokenPath, data, 0600); err != nil {
+		return err
+	}
+	c.token = token
+	return nil
+}
+
+func (c *Client) LoadToken() (*StoredToken, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.loadToken()
+}
+
+func (c *Client) loadToken() (*StoredToken, error) {
+	if c.token != nil {
+		return c.token, nil
+	}
+	data, err := os.ReadFile(c.TokenPath)
+	if err != nil {
+		return nil, err
+	}
+	var token StoredToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+	c.token = &token
+	return c.token, nil
+}
+
+// AccessToken returns a valid access token, refreshing it if it expires
+// within five minutes.
+func (c *Client) AccessToken(ctx context.Context) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	token, err := c.loadToken()
+	if err != nil {
+		return "", fmt.Errorf("not authenticated - run 'oura auth' first")
+	}
+
+	if remaining := time.Until(token.ExpiresAt); remaining < 5*time.Minute {
+		reason := "token expired"
+		if remaining > 0 {
+			reason = fmt.Sprintf("token expires in %s", formatDuration(int(remaining.Seconds())))
+		}
+		newToken, err := c.refresh(ctx, token.RefreshToken)
+		if err != nil {
+			if *verboseFlag {
+				fmt.Fprintf(os.Stderr, "%s, refresh failed\n", reason)
+			}
+			var oauthErr *OAuthError
+			if errors.As(err, &oauthErr) && oauthErr.Code != "" {
+				return "", err
+			}
+			return "", fmt.Errorf("token refresh failed - run 'oura auth' again: %v", err)
+		}
+		token = newToken
+		if *verboseFlag {
+			fmt.Fprintf(os.Stderr, "%s, refreshed (valid for %s)\n", reason, formatDuration(int(time.Until(token.ExpiresAt).Seconds())))
+		}
+	} else if *verboseFlag {
+		fmt.Fprintf(os.Stderr, "using stored token (valid for %s)\n", formatDuration(int(remaining.Seconds())))
+	}
+
+	return token.AccessToken, nil
+}
+
+// Refresh exchanges a refresh token for a new token and saves it.
+func (c *Client) Refresh(ctx context.Context, refresh string) (*StoredToken, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.refresh(ctx, refresh)
+}
+
+func (c *Client) refresh(ctx context.Context, refresh string) (*StoredToken, error) {
+	data := url.Values{}
+	data.Set("grant_type", "refresh_token")
+	data.Set("refresh_token", refresh)
+	data.Set("client_id", c.Config.ClientID)
+	data.Set("client_secret", c.Config.ClientSecret)
+
+	stored, err := c.requestToken(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("token refresh failed: %w", err)
+	}
+	return stored, nil
+}
+
+// Exchange trades an authorization code for a token and saves it.
+func (c *Client) Exchange(ctx context.Context, code, redirectURI string) (*StoredToken, error) {
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", code)
+	data.Set("redirect_uri", redirectURI)
+	data.Set("client_id", c.Config.ClientID)
+	data.Set("client_secret", c.Config.ClientSecret)
+
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.requestToken(ctx, data)
+}
+
+// requestToken is called with tokenMu held.
+func (c *Client) requestToken(ctx context.Context, data url.Values) (*StoredToken, error) {
+	req, err := c.newRequest(ctx, "POST", c.TokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		if *verboseFlag {
+			fmt.Fprintf(os.Stderr, "token endpoint returned %d: %s\n", resp.StatusCode, body)
+		}
+		oauthErr := &OAuthError{Body: body}
+		json.Unmarshal(body, oauthErr)
+		return nil, oauthErr
+	}
+
+	var tokenResp TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to parse token: %v", err)
+	}
+
+	stored := &StoredToken{
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+	}
+	if err := c.saveToken(stored); err != nil {
+		return nil, fmt.Errorf("failed to save token: %v", err)
+	}
+
+	return stored, nil
+}
+
+// Get performs an authenticated GET against the usercollection API and
+// returns the body of a 200 response.
+func (c *Client) Get(ctx context.Context, endpoint string, params url.Values) ([]byte, error) {
+	if c.RecordTimings {
+		start := time.Now()
+		defer func() {
+			c.timingsMu.Lock()
+			c.Timings = append(c.Timings, Timing{endpoint, time.Since(start)})
+			c.timingsMu.Unlock()
+		}()
+	}
+
+	url := c.APIBase + endpoint
+	if len(params) > 0 {
+		url += "?" + params.Encode()
+	}
+
+	if c.Offline {
+		body, err := c.readCache(url)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", endpoint, ErrNotCached)
+		}
+		return body, nil
+	}
+
+	// Revalidate a cached response instead of downloading it again.
+	c.cacheMu.Lock()
+	cached, cacheErr := c.readCache(url)
+	etag := ""
+	if cacheErr == nil {
+		etag = c.readETag(url)
+	}
+	c.cacheMu.Unlock()
+
+	resp, err := c.authorizedGet(ctx, url, etag)
+	if err != nil {
+		return nil, err
+	}
+	// A token the API rejects before its expiry (revoked, or the clock is
+	// off) is refreshed once and the request repeated.
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		rejected := strings.TrimPrefix(resp.Request.Header.Get("Authorization"), "Bearer ")
+		if err := c.refreshRejected(ctx, rejected); err != nil {
+			return nil, err
+		}
+		if resp, err = c.authorizedGet(ctx, url, etag); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cacheErr == nil {
+		return cached, nil
+	}
+	if resp.StatusCode != 200 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: body}
+	}
+
+	c.cacheMu.Lock()
+	c.writeCache(url, body)
+	c.writeETag(url, resp.Header.Get("ETag"))
+	c.cacheMu.Unlock()
+	return body, nil
+}
+
+// refreshRejected refreshes the token after the API rejected the access
+// token rejected, unless another request already replaced it.
+func (c *Client) refreshRejected(ctx context.Context, rejected string) error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	token, err := c.loadToken()
+	if err != nil {
+		return err
+	}
+	if token.AccessToken != rejected {
+		return nil
+	}
+	_, err = c.refresh(ctx, token.RefreshToken)
+	return err
+}
+
+// readBody reads a response body, decompressing it if it's still gzip.
+// The transport only decompresses when it added Accept-Encoding itself,
+// not when a request (or a proxy in between) set it.
+func readBody(resp *http.Response) ([]byte, error) {
+	if resp.Uncompressed || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return io.ReadAll(resp.Body)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err == io.EOF {
+		return nil, nil // an empty body, e.g. a 304
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
+// authorizedGet sends a GET with the current access token and, if etag
+// is set, asks for 304 Not Modified when it still matches.
+func (c *Client) authorizedGet(ctx context.Context, url, etag string) (*http.Response, error) {
+	token, err := c.AccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	return c.do(ctx, req)
+}
+
+// maxRateLimitRetries is how often a 429 response is retried.
+const maxRateLimitRetries = 5
+
+// do sends req, waiting and retrying while the API answers 429 Too Many
+// Requests or the network fails transiently (up to MaxRetries). Both
+// back off from one second, doubling; a 429's Retry-After wins. Every
+// attempt first waits its turn with Limiter.
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	wait := time.Second
+	rateLimited, netFailures := 0, 0
+	for {
+		if c.Limiter != nil {
+			if err := c.Limiter.Wait(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, err
+			}
+		}
+		resp, err := c.HTTP.Do(req)
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || !isTransient(err) {
+				return nil, err
+			}
+			if netFailures >= c.MaxRetries {
+				if netFailures == 0 {
+					return nil, err
+				}
+				return nil, fmt.Errorf("gave up after %d retries: %w", netFailures, err)
+			}
+			netFailures++
+			delay = wait
+			if *verboseFlag {
+				fmt.Fprintf(os.Stderr, "network error (%v), retrying in %s\n", err, delay)
+			}
+		case resp.StatusCode == http.StatusTooManyRequests && rateLimited < maxRateLimitRetries:
+			resp.Body.Close()
+			rateLimited++
+			delay = wait
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+				delay = time.Duration(secs) * time.Second
+			}
+			if *verboseFlag {
+				fmt.Fprintf(os.Stderr, "rate limited, retrying in %s\n", delay)
+			}
+		default:
+			return resp, nil
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+// isTransient reports whether a request error is worth retrying: timeouts,
+// temporary DNS failures and dropped connections. Errors that will
+// recur, like an unknown host or a refused connection, are not.
+func isTransient(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.ENETUNREACH) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// cachePath returns the cache file for a request URL.
+func (c *Client) cachePath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.CacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *Client) readCache(url string) ([]byte, error) {
+	if c.CacheDir == "" {
+		return nil, ErrNotCached
+	}
+	return os.ReadFile(c.cachePath(url))
+}
+
+// writeCache stores a response body. Failures only cost a later cache
+// miss, so they are ignored.
+func (c *Client) writeCache(url string, body []byte) {
+	if c.CacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(c.CacheDir, 0700); err != nil {
+		return
+	}
+	os.WriteFile(c.cachePath(url), body, 0600)
+}
+
+// etagPath returns the file holding the ETag of a cached response.
+func (c *Client) etagPath(url string) string {
+	return strings.TrimSuffix(c.cachePath(url), ".json") + ".etag"
+}
+
+func (c *Client) readETag(url string) string {
+	if c.CacheDir == "" {
+		return ""
+	}
+	data, err := os.ReadFile(c.etagPath(url))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// writeETag stores the response's ETag, or removes a stale one when the
+// response had none.
+func (c *Client) writeETag(url, etag string) {
+	if c.CacheDir == "" {
+		return
+	}
+	if etag == "" {
+		os.Remove(c.etagPath(url))
+		return
+	}
+	os.WriteFile(c.etagPath(url), []byte(etag), 0600)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for an httptest server running h, with
+// its token and cache in a temp config dir. The stored token is "old".
+func newTestClient(t *testing.T, h http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	c := &Client{
+		HTTP:      srv.Client(),
+		APIBase:   srv.URL + "/v2/usercollection",
+		TokenURL:  srv.URL + "/oauth/token",
+		TokenPath: filepath.Join(dir, "token.json"),
+		CacheDir:  filepath.Join(dir, "cache"),
+		UserAgent: "oura-cli/test",
+	}
+	err := c.SaveToken(&StoredToken{AccessToken: "old", RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// tokenHandler answers a refresh with the access token "new".
+func tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh" {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(TokenResponse{AccessToken: "new", RefreshToken: "refresh2", ExpiresIn: 3600})
+}
+
+func TestClientGet(t *testing.T) {
+	tests := []struct {
+		name string
+		// api serves /v2/usercollection/daily_sleep; calls counts requests.
+		api   func(calls int, w http.ResponseWriter, r *http.Request)
+		gets  int // Get is called this often; the last result is checked
+		want  string
+		token string        // access token stored afterwards
+		wait  time.Duration // Get takes at least this long
+	}{
+		{
+			name: "refreshes the token on 401",
+			api: func(calls int, w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer new" {
+					http.Error(w, `{"detail":"expired"}`, http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte(`{"data":[]}`))
+			},
+			gets:  1,
+			want:  `{"data":[]}`,
+			token: "new",
+		},
+		{
+			name: "serves the cache on 304",
+			api: func(calls int, w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.Header().Set("ETag", `"v1"`)
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				if calls > 1 {
+					// Not revalidated: the cached body would be bypassed.
+					w.Write([]byte(`{"data":"refetched"}`))
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				w.Write([]byte(`{"data":[{"day":"2026-01-01"}]}`))
+			},
+			gets:  2,
+			want:  `{"data":[{"day":"2026-01-01"}]}`,
+			token: "old",
+		},
+		{
+			name: "retries 429 after Retry-After",
+			api: func(calls int, w http.ResponseWriter, r *http.Request) {
+				if calls == 1 {
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.Write([]byte(`{"data":[]}`))
+			},
+			gets:  1,
+			want:  `{"data":[]}`,
+			token: "old",
+			wait:  time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			mux := http.NewServeMux()
+			mux.HandleFunc("/oauth/token", tokenHandler)
+			mux.HandleFunc("/v2/usercollection/daily_sleep", func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				tt.api(calls, w, r)
+			})
+			c := newTestClient(t, mux)
+
+			var body []byte
+			var err error
+			start := time.Now()
+			for range tt.gets {
+				body, err = c.Get(context.Background(), "/daily_sleep", singleDay("2026-01-01"))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed < tt.wait {
+				t.Errorf("took %s, want at least %s", elapsed, tt.wait)
+			}
+			if string(body) != tt.want {
+				t.Errorf("body = %s, want %s", body, tt.want)
+			}
+
+			data, err := os.ReadFile(c.TokenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stored StoredToken
+			if err := json.Unmarshal(data, &stored); err != nil {
+				t.Fatal(err)
+			}
+			if stored.AccessToken != tt.token {
+				t.Errorf("stored access token = %q, want %q", stored.AccessToken, tt.token)
+			}
+		})
+	}
+}
+
+// TestClientGetConcurrent shares a client between goroutines, as range
+// fetches do; run with -race. A token rejected by every request at once
+// is refreshed only once.
+func TestClientGetConcurrent(t *testing.T) {
+	var refreshes atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		refreshes.Add(1)
+		tokenHandler(w, r)
+	})
+	mux.HandleFunc("/v2/usercollection/daily_sleep", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new" {
+			http.Error(w, `{"detail":"expired"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("ETag", `"`+r.URL.Query().Get("start_date")+`"`)
+		w.Write([]byte(`{"data":[]}`))
+	})
+	c := newTestClient(t, mux)
+	c.RecordTimings = true
+
+	const n = 8
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			day := time.Date(2026, 1, 1+i, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+			if _, err := c.Get(context.Background(), "/daily_sleep", singleDay(day)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := refreshes.Load(); got != 1 {
+		t.Errorf("refreshed %d times, want 1", got)
+	}
+	if len(c.Timings) != n {
+		t.Errorf("recorded %d timings, want %d", len(c.Timings), n)
+	}
+}
+
+// setAcceptEncoding sets Accept-Encoding itself, which stops the
+// transport from decompressing the response.
+type setAcceptEncoding struct{ next http.RoundTripper }
+
+func (s setAcceptEncoding) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Accept-Encoding", "gzip")
+	return s.next.RoundTrip(r)
+}
+
+func TestClientGetGzip(t *testing.T) {
+	const want = `{"data":[{"bpm":60,"source":"awake","timestamp":"2026-01-01T00:00:00+00:00"}]}`
+	for _, explicit := range []bool{false, true} {
+		name := "transparent"
+		if explicit {
+			name = "Accept-Encoding set"
+		}
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+					t.Error("request didn't accept gzip")
+				}
+				w.Header().Set("Content-Encoding", "gzip")
+				gz := gzip.NewWriter(w)
+				gz.Write([]byte(want))
+				gz.Close()
+			}))
+			if explicit {
+				c.HTTP.Transport = setAcceptEncoding{c.HTTP.Transport}
+			}
+
+			body, err := c.Get(context.Background(), "/heartrate", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != want {
+				t.Errorf("body = %q, want the decompressed JSON", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ANSI colors for output that reads as good or bad at a glance.
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+	colorCyan   = "36"
+)
+
+// useColor reports whether stdout gets colors: only on a terminal, and
+// never with --no-color, NO_COLOR set (https://no-color.org) or TERM=dumb.
+func useColor() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || *noColorFlag || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// colorize wraps s in color when useColor allows. An empty color leaves
+// s plain.
+func colorize(color, s string) string {
+	if color == "" || !useColor() {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
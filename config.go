@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// configKeys returns Config's JSON keys and their field types.
+func configKeys() map[string]reflect.Type {
+	keys := map[string]reflect.Type{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		keys[strings.Split(f.Tag.Get("json"), ",")[0]] = f.Type
+	}
+	return keys
+}
+
+// doConfig gets and sets config keys without hand-editing the JSON. It
+// runs before loadConfig, so it works on a new or incomplete config.
+func doConfig() {
+	keys := configKeys()
+	var names []string
+	for k := range keys {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: oura config get KEY")
+		fmt.Fprintln(os.Stderr, "       oura config set KEY VALUE")
+		fmt.Fprintln(os.Stderr, "       oura config path")
+		fmt.Fprintf(os.Stderr, "keys: %s\n", strings.Join(names, ", "))
+		os.Exit(1)
+	}
+	if len(args) < 1 {
+		usage()
+	}
+
+	path := getConfigPath()
+	raw, err := readRawConfig(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch {
+	case args[0] == "path" && len(args) == 1:
+		fmt.Println(path)
+	case args[0] == "get" && len(args) == 2:
+		v, ok := raw[args[1]]
+		if !ok {
+			if _, known := keys[args[1]]; known {
+				fmt.Fprintf(os.Stderr, "%s is not set\n", args[1])
+			} else {
+				fmt.Fprintf(os.Stderr, "unknown key %q (want one of: %s)\n", args[1], strings.Join(names, ", "))
+			}
+			os.Exit(1)
+		}
+		// Strings print bare, for use in scripts; anything else as JSON.
+		var s string
+		if json.Unmarshal(v, &s) == nil {
+			fmt.Println(s)
+		} else {
+			var out bytes.Buffer
+			json.Compact(&out, v)
+			fmt.Println(out.String())
+		}
+	case args[0] == "set" && len(args) == 3:
+		t, ok := keys[args[1]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown key %q (want one of: %s)\n", args[1], strings.Join(names, ", "))
+			os.Exit(1)
+		}
+		v, err := configValue(t, args[2])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid value for %s: %v\n", args[1], err)
+			os.Exit(1)
+		}
+		raw[args[1]] = v
+		if err := writeRawConfig(path, raw); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		usage()
+	}
+}
+
+// readRawConfig reads the config as raw values, so keys this version
+// doesn't know survive a rewrite. A missing file is an empty config.
+func readRawConfig(path string) (map[string]json.RawMessage, error) {
+	raw := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return raw, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return raw, nil
+}
+
+// writeRawConfig replaces the config through a temporary file, so an
+// interrupted write can't leave it truncated. The result is 0600 even if
+// the old file wasn't, since it holds the client secret.
+func writeRawConfig(path string, raw map[string]json.RawMessage) error {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	json.Indent(&out, data, "", "  ")
+	out.WriteString("\n")
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// configValue converts a command-line value to JSON for a field of type
+// t. Lists are comma-separated; structs take a JSON object.
+func configValue(t reflect.Type, s string) (json.RawMessage, error) {
+	var v any
+	var err error
+	switch t.Kind() {
+	case reflect.String:
+		v = s
+	case reflect.Int:
+		v, err = strconv.Atoi(s)
+	case reflect.Bool:
+		v, err = strconv.ParseBool(s)
+	case reflect.Float64:
+		v, err = strconv.ParseFloat(s, 64)
+	case reflect.Slice:
+		v = strings.Split(s, ",")
+	default:
+		// Check the JSON fits the field before writing it.
+		ptr := reflect.New(t)
+		if err := json.Unmarshal([]byte(s), ptr.Interface()); err != nil {
+			return nil, err
+		}
+		return json.RawMessage(s), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+// doDoctor runs a checklist of common setup problems and exits non-zero
+// if any check fails.
+func doDoctor() {
+	failed := 0
+	check := func(ok bool, format string, a ...any) bool {
+		mark := sym.Check
+		if !ok {
+			mark = sym.Cross
+			failed++
+		}
+		fmt.Printf("%s %s\n", mark, fmt.Sprintf(format, a...))
+		return ok
+	}
+
+	configPath := getConfigPath()
+	if _, err := os.Stat(configPath); err != nil {
+		check(false, "Config file missing: %s", configPath)
+	} else if err := loadConfig(); err != nil {
+		check(false, "Config file invalid: %v", err)
+	} else {
+		check(true, "Config file: %s (client_id and client_secret set)", configPath)
+	}
+
+	client = NewClient(config)
+	token, err := client.LoadToken()
+	if err != nil {
+		check(false, "Token missing or unreadable - run 'oura auth'")
+	} else if remaining := time.Until(token.ExpiresAt); remaining > 0 {
+		check(true, "Token valid until %s (%s left)", token.ExpiresAt.Local().Format("2006-01-02 15:04"), remaining.Round(time.Minute))
+	} else {
+		check(token.RefreshToken != "", "Token expired %s, refresh token present", token.ExpiresAt.Local().Format("2006-01-02 15:04"))
+	}
+
+	host := "api.ouraring.com"
+	if u, err := url.Parse(apiBase); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, "443")
+	}
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	networkOK := check(err == nil, "Network: %s reachable", host)
+	if err == nil {
+		conn.Close()
+	} else {
+		fmt.Printf("    %v\n", err)
+	}
+
+	if networkOK && token != nil {
+		_, err := client.Get(ctx, "/personal_info", nil)
+		if !check(err == nil, "API: /personal_info request") {
+			fmt.Printf("    %v\n", err)
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("%d check(s) failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("All checks passed")
+}
+
+// doCheck is a liveness probe for ring syncing, for cron and monitoring:
+// it exits 0 only if the newest heart rate reading is within --max-age.
+func doCheck() {
+	days := int(*maxAgeFlag/(24*time.Hour)) + 2
+	latest, err := latestReading(time.Now().Format("2006-01-02"), days)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "CRITICAL: %v\n", err)
+		os.Exit(2)
+	}
+	if latest.IsZero() {
+		fmt.Printf("STALE: no heart rate readings in the last %d days\n", days)
+		os.Exit(1)
+	}
+	// Clamp clock skew between the ring and this machine.
+	age := max(time.Since(latest), 0).Round(time.Minute)
+	if age > *maxAgeFlag {
+		fmt.Printf("STALE: last reading %s ago (max %s)\n", age, *maxAgeFlag)
+		os.Exit(1)
+	}
+	fmt.Printf("OK: last reading %s ago\n", age)
+}
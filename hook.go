@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// fetchCommands are the commands after which the on_fetch hook runs.
+var fetchCommands = map[string]bool{
+	"today": true, "all": true, "sleep": true, "activity": true,
+	"readiness": true, "heartrate": true, "stress": true, "spo2": true,
+	"resilience": true, "vo2": true, "workout": true, "summary": true,
+}
+
+// runFetchHook runs the on_fetch executable with the summary of the days
+// just fetched on stdin, in summary's JSON shape: one object for a single
+// day, an array for several. It's best effort; failures are only
+// reported with --verbose.
+func runFetchHook() {
+	days := hookDays()
+	if len(days) == 0 {
+		return
+	}
+	s, err := loadSeries(days[0], days[len(days)-1])
+	if err != nil {
+		hookFailed(err)
+		return
+	}
+	var out []DaySummary
+	for _, day := range days {
+		out = append(out, daySummary(s, day))
+	}
+	var data []byte
+	if len(out) == 1 {
+		data, err = json.Marshal(out[0])
+	} else {
+		data, err = json.Marshal(out)
+	}
+	if err != nil {
+		hookFailed(err)
+		return
+	}
+
+	cmd := exec.CommandContext(ctx, config.OnFetch)
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	// The hook's output goes to stderr, keeping stdout the command's own.
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		hookFailed(err)
+	}
+}
+
+// hookDays returns the days the command fetched: the range, the date
+// arguments, or today.
+func hookDays() []string {
+	if rangeRequested() {
+		from, to, err := parseRangeFlags()
+		if err != nil {
+			return nil
+		}
+		return daysBetween(from, to)
+	}
+	if len(args) > 0 {
+		return getDateArgs()
+	}
+	return []string{time.Now().Format("2006-01-02")}
+}
+
+func hookFailed(err error) {
+	if *verboseFlag {
+		fmt.Fprintf(os.Stderr, "on_fetch hook %s: %v\n", config.OnFetch, err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+type SessionResponse struct {
+	Data []SessionRecord `json:"data"`
+}
+
+// SessionRecord is a guided or unguided session (meditation, breathing,
+// rest) started in the Oura app.
+type SessionRecord struct {
+	ID            string `json:"id"`
+	Day           string `json:"day"`
+	Type          string `json:"type"`
+	StartDatetime string `json:"start_datetime"`
+	EndDatetime   string `json:"end_datetime"`
+	Mood          string `json:"mood"`
+}
+
+// icalEvent is one VEVENT of the calendar.
+type icalEvent struct {
+	UID         string
+	Start, End  time.Time
+	Summary     string
+	Description string
+}
+
+// exportICal writes the workouts and sessions in from..to to w as an
+// iCalendar feed, for importing training into a calendar.
+func exportICal(w io.Writer, from, to string) error {
+	var events []icalEvent
+	warnedScope := false
+	for _, chunk := range chunkRange(from, to, backfillChunkDays) {
+		var workouts WorkoutResponse
+		if err := getRange("/workout", chunk[0], chunk[1], &workouts); err != nil {
+			return err
+		}
+		for _, wo := range filterWorkouts(workouts.Data) {
+			if wo.Day < chunk[0] || wo.Day > chunk[1] {
+				continue
+			}
+			summary := wo.Activity
+			if wo.Label != nil && *wo.Label != "" {
+				summary = *wo.Label
+			}
+			desc := fmt.Sprintf("Calories: %.0f\nIntensity: %s\nSource: %s", wo.Calories, wo.Intensity, wo.Source)
+			if wo.Distance > 0 {
+				desc = fmt.Sprintf("Distance: %s\n", formatDistance(wo.Distance, 2)) + desc
+			}
+			events = appendEvent(events, "workout", wo.ID, wo.StartDatetime, wo.EndDatetime, titleCase(summary), desc)
+		}
+
+		// Sessions need their own scope; without it, export workouts only.
+		var sessions SessionResponse
+		if err := getRange("/session", chunk[0], chunk[1], &sessions); isScopeError(err) {
+			if !warnedScope {
+				fmt.Fprintln(os.Stderr, "sessions: skipped (insufficient scope)")
+				warnedScope = true
+			}
+		} else if err != nil {
+			return err
+		}
+		for _, s := range sessions.Data {
+			if s.Day < chunk[0] || s.Day > chunk[1] {
+				continue
+			}
+			desc := "Oura session"
+			if s.Mood != "" {
+				desc += "\nMood: " + s.Mood
+			}
+			events = appendEvent(events, "session", s.ID, s.StartDatetime, s.EndDatetime, titleCase(s.Type), desc)
+		}
+	}
+	sortByTime(events, func(e icalEvent) string { return e.Start.Format(time.RFC3339) })
+
+	// iCalendar lines end in CRLF.
+	var b strings.Builder
+	line := func(s string) { b.WriteString(foldICalLine(s) + "\r\n") }
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//oura-cli//Oura workouts//EN")
+	line("CALSCALE:GREGORIAN")
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID + "@oura-cli")
+		line("DTSTAMP:" + stamp)
+		line("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+		line("DTEND:" + e.End.UTC().Format("20060102T150405Z"))
+		line("SUMMARY:" + escapeICal(e.Summary))
+		line("DESCRIPTION:" + escapeICal(e.Description))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// appendEvent adds an event, skipping records without valid times. The
+// UID comes from the record's ID, so re-importing updates events rather
+// than duplicating them.
+func appendEvent(events []icalEvent, kind, id, start, end, summary, desc string) []icalEvent {
+	s, err1 := time.Parse(time.RFC3339, start)
+	e, err2 := time.Parse(time.RFC3339, end)
+	if err1 != nil || err2 != nil {
+		return events
+	}
+	if id == "" {
+		id = s.UTC().Format("20060102T150405Z")
+	}
+	uid := kind + "-" + id
+	return append(events, icalEvent{UID: uid, Start: s, End: e, Summary: summary, Description: desc})
+}
+
+// titleCase turns an API name like "strength_training" into "Strength training".
+func titleCase(s string) string {
+	s = strings.ReplaceAll(s, "_", " ")
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// escapeICal escapes a TEXT value (RFC 5545 section 3.3.11).
+func escapeICal(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldICalLine splits lines longer than 75 octets, continuing them on
+// lines starting with a space, without splitting a UTF-8 character.
+func foldICalLine(s string) string {
+	const limit = 75
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
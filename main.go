@@ -1,100 +1,514 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
 )
 
+// The auth flow's local callback server. The path can be changed with
+// "callback_path" in config to match the app's registered redirect URI.
 const (
-	redirectURI = "http://localhost:8081/callback"
-	authURL     = "https://cloud.ouraring.com/oauth/authorize"
-	tokenURL    = "https://api.ouraring.com/oauth/token"
-	apiBase     = "https://api.ouraring.com/v2/usercollection"
+	callbackAddr        = "localhost:8081"
+	defaultCallbackPath = "/callback"
+
+	// authShutdownGrace bounds how long the callback server waits for
+	// the browser's response to finish sending.
+	authShutdownGrace = 5 * time.Second
+)
+
+const defaultSuccessHTML = `<html><body><h1>✓ Authenticated!</h1><p>You can close this tab.</p></body></html>`
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// Oura endpoints. They can be overridden with OURA_AUTH_URL,
+// OURA_TOKEN_URL and OURA_API_BASE (or --api-base) to point the CLI at a
+// mock server or proxy.
+var (
+	authURL  = envOr("OURA_AUTH_URL", "https://cloud.ouraring.com/oauth/authorize")
+	tokenURL = envOr("OURA_TOKEN_URL", "https://api.ouraring.com/oauth/token")
+	apiBase  = envOr("OURA_API_BASE", "https://api.ouraring.com/v2/usercollection")
 )
 
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
 type Config struct {
-	ClientID     string `json:"client_id"`
-	ClientSecret string `json:"client_secret"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	StepGoal     int      `json:"step_goal"`
+	StreakScore  int      `json:"streak_score"`
+	Scopes       []string `json:"scopes"`
+	ASCII        bool     `json:"ascii"`
+
+	METWeights METWeights `json:"met_weights"`
+
+	CallbackPath    string `json:"callback_path"`
+	AuthSuccessHTML string `json:"auth_success_html"`
+
+	UserAgent string `json:"user_agent"`
+
+	// Units is metric (the default) or imperial, for distances and
+	// temperatures. Timezone, an IANA name, replaces the system's for
+	// dates and clock times.
+	Units    string `json:"units"`
+	Timezone string `json:"timezone"`
+
+	SleepGoalHours     float64 `json:"sleep_goal_hours"`
+	MinSleepEfficiency int     `json:"min_sleep_efficiency"`
+
+	OnFetch string `json:"on_fetch"`
+
+	// PinSHA256 lists accepted server public keys: base64 SHA-256 of
+	// each key's SubjectPublicKeyInfo.
+	PinSHA256 []string `json:"pin_sha256"`
+
+	RedactKeys []string `json:"redact_keys"`
+}
+
+// callbackPath returns the configured callback path, or the default.
+func callbackPath() string {
+	if config.CallbackPath == "" {
+		return defaultCallbackPath
+	}
+	return config.CallbackPath
+}
+
+func redirectURI() string {
+	return "http://" + callbackAddr + callbackPath()
+}
+
+const defaultStepGoal = 10000
+
+// stepGoal returns the configured step goal, or the default.
+func stepGoal() int {
+	if config.StepGoal <= 0 {
+		return defaultStepGoal
+	}
+	return config.StepGoal
+}
+
+// METWeights are the MET values --met-minutes gives each activity level.
+// Unset levels use defaultMETWeights.
+type METWeights struct {
+	High   float64 `json:"high"`
+	Medium float64 `json:"medium"`
+	Low    float64 `json:"low"`
+}
+
+var defaultMETWeights = METWeights{High: 6, Medium: 4, Low: 2}
+
+// metMinutes weights the minutes at each activity level by its MET value,
+// giving one effort number comparable across days.
+func metMinutes(a *ActivityRecord) float64 {
+	w := config.METWeights
+	if w.High <= 0 {
+		w.High = defaultMETWeights.High
+	}
+	if w.Medium <= 0 {
+		w.Medium = defaultMETWeights.Medium
+	}
+	if w.Low <= 0 {
+		w.Low = defaultMETWeights.Low
+	}
+	return (w.High*float64(a.HighActivityTime) +
+		w.Medium*float64(a.MediumActivityTime) +
+		w.Low*float64(a.LowActivityTime)) / 60
 }
 
 var config Config
 
+// ctx is cancelled on SIGINT so in-flight requests abort cleanly.
+var ctx = context.Background()
+
+// Command-line flags. They may appear anywhere after the command name.
+var (
+	flags       = flag.NewFlagSet("oura", flag.ExitOnError)
+	chartFlag   = flags.Bool("chart", false, "show contributors as bar charts")
+	goalFlag    = flags.Bool("goal", false, "show activity goal progress")
+	hourlyFlag  = flags.Bool("hourly", false, "show activity intensity hour by hour")
+	streakFlag  = flags.Bool("streak", false, "show the current and longest run of days meeting the step goal")
+	metMinFlag  = flags.Bool("met-minutes", false, "show activity as MET-weighted minutes")
+	explainFlag = flags.Bool("explain", false, "describe each readiness and sleep contributor")
+
+	baselineFlag          = flags.String("baseline", "", "in today/all, compare scores with their mean over the previous window (e.g. 30d)")
+	yesterdayFallbackFlag = flags.Bool("yesterday-fallback", false, "in today/all, show yesterday's data for metrics with none yet")
+
+	hypnogramFlag   = flags.Bool("hypnogram", false, "draw each sleep period's stages across the night")
+	hrvChartFlag    = flags.Bool("hrv-chart", false, "sparkline each sleep period's overnight HRV")
+	regularityFlag  = flags.Bool("regularity", false, "with sleep, report bedtime consistency over --from/--to")
+	byWeekdayFlag   = flags.Bool("by-weekday", false, "with stats, show the mean for each day of the week")
+	nightsFlag      = flags.Int("nights", 0, "with sleep, a table of the last N nights' scores and totals")
+	includeNapsFlag = flags.Bool("include-naps", true, "count naps in sleep periods and totals (--include-naps=false for main sleep only)")
+
+	compactFlag = flags.Bool("compact", false, "fit all metrics on one screen")
+	rollingFlag = flags.Bool("rolling", false, "add 7- and 30-day rolling averages to summary")
+	profileFlag = flags.String("profile", "", "use a named profile's config and token")
+	configFlag  = flags.String("config", "", "config file to use; token and state files live beside it")
+	verboseFlag = flags.Bool("verbose", false, "print diagnostic details to stderr")
+	apiBaseFlag = flags.String("api-base", "", "override the API base URL")
+	offlineFlag = flags.Bool("offline", false, "serve data only from the local cache")
+	proxyFlag   = flags.String("proxy", "", "proxy URL for all requests (overrides HTTP(S)_PROXY)")
+	uaFlag      = flags.String("user-agent", "", "User-Agent header for all requests (default oura-cli/VERSION)")
+	scopesFlag  = flags.String("scopes", "", "comma-separated OAuth scopes to request in auth")
+
+	maxRetriesFlag  = flags.Int("max-retries", 3, "retries for transient network errors (DNS, dropped connections)")
+	concurrencyFlag = flags.Int("concurrency", 4, "requests in flight at once when fetching a sleep range")
+	qpsFlag         = flags.Float64("qps", 5, "most API requests per second across the whole run (0: no limit)")
+	timingsFlag     = flags.Bool("timings", false, "print how long each API call took to stderr, slowest first")
+
+	forceRefreshFlag = flags.Bool("force-refresh", false, "refresh the access token before running the command")
+	dryRunFlag       = flags.Bool("dry-run", false, "with auth, print the authorization URL without starting the flow")
+
+	asciiFlag   = flags.Bool("ascii", false, "use plain ASCII instead of emoji and box drawing")
+	noColorFlag = flags.Bool("no-color", false, "disable colored output (also NO_COLOR)")
+
+	clock24Flag   = flags.Bool("24h", false, "show times on a 24-hour clock")
+	precisionFlag = flags.Int("precision", -1, "decimal places for averages, distances and other floats")
+	secondsFlag   = flags.Bool("seconds", false, "show seconds in durations")
+
+	wideFlag  = flags.Bool("wide", false, "adapt layouts to the terminal width")
+	widthFlag = flags.Int("width", 0, "lay out for exactly N columns (implies --wide)")
+
+	fromFlag   = flags.String("from", "", "start date for range commands")
+	toFlag     = flags.String("to", "", "end date for range commands (default: today)")
+	sinceFlag  = flags.String("since", "", "range of the last N days (7d) or weeks (2w), ending today")
+	untilFlag  = flags.String("until", "", "with --since, end the range N days (1d) or weeks (1w) ago")
+	dbFlag     = flags.String("db", "oura.db", "SQLite database file for export")
+	outFlag    = flags.String("out", ".", "directory for CSV export files")
+	appendFlag = flags.Bool("append", false, "append new days to existing export files")
+
+	formatFlag   = flags.String("format", "json", "output format: json or jsonl for summary, md for report, table for daily views")
+	templateFlag = flags.String("template", "", "render each summary day with a Go text/template")
+	schemaFlag   = flags.Bool("schema", false, "with summary, print the JSON Schema of its output")
+	groupByFlag  = flags.String("group-by", "", "with summary over a range, aggregate by week or month")
+
+	anonymizeFlag   = flags.Bool("anonymize", false, "in json, summary and raw output, blank IDs and emails and make dates relative")
+	redactFlag      = flags.Bool("redact", false, "in json and raw output, blank the values of sensitive keys (redact_keys)")
+	jsonCompactFlag = flags.Bool("json-compact", false, "print json, summary and raw output as single-line JSON")
+
+	sinceLastFlag   = flags.Bool("since-last", false, "export only days since the last successful export")
+	onlyMissingFlag = flags.Bool("only-missing", false, "with a daily command and a range, list days with no data")
+
+	scoreOnlyFlag    = flags.Bool("score-only", false, "with sleep, activity or readiness, print just the day's score")
+	caloriesOnlyFlag = flags.Bool("calories-only", false, "with activity, print just the day's active calories")
+	stepsOnlyFlag    = flags.Bool("steps-only", false, "with activity, print just the day's steps")
+
+	paramFlag = listFlag("param", "with raw, a query parameter key=value (repeatable)")
+
+	rawFlag   = flags.Bool("raw", false, "list individual heart rate readings")
+	limitFlag = flags.Int("limit", 0, "with --raw, show the first N readings (negative: last N)")
+	everyFlag = flags.Duration("every", 0, "with --raw, keep one reading per interval (e.g. 5m)")
+
+	onlyFlag     = flags.String("only", "", "with all/today, comma-separated sections to show")
+	skipFlag     = flags.String("skip", "", "with all/today, comma-separated sections to leave out")
+	averageFlag  = flags.String("average", "simple", "heart rate average: simple or weighted")
+	intervalFlag = flags.Duration("interval", 0, "with heartrate, show min/avg/max per interval of the day (e.g. 1h)")
+	stdDevFlag   = flags.Bool("stddev", false, "with heartrate, also show the standard deviation of BPM")
+	restingFlag  = flags.Bool("resting", false, "with heartrate, estimate resting heart rate from the readings")
+	metricFlag   = flags.String("metric", "", "with watch, the metric to check (e.g. readiness)")
+	belowFlag    = flags.Float64("below", 0, "with watch, alert when the metric is below this")
+	aboveFlag    = flags.Float64("above", 0, "with watch, alert when the metric is above this")
+	notifyFlag   = flags.Bool("notify", false, "with watch, also send a desktop notification")
+	maxAgeFlag   = flags.Duration("max-age", 18*time.Hour, "with check, the oldest acceptable heart rate reading")
+
+	sourceFlag      = flags.String("source", "", "only show workouts from this source (e.g. manual)")
+	activityFlag    = flags.String("activity", "", "only show workouts of this activity (e.g. running)")
+	minDurationFlag = flags.Duration("min-duration", 0, "only show workouts at least this long (e.g. 20m)")
+)
+
+// args holds the positional arguments, starting with the command name.
+var args []string
+
+// parseArgs parses flags interspersed with positional arguments.
+func parseArgs(argv []string) {
+	for {
+		flags.Parse(argv)
+		rest := flags.Args()
+		// "--" ends flag parsing for good, e.g. for a page or endpoint
+		// starting with a dash.
+		if consumed := len(argv) - len(rest); consumed > 0 && argv[consumed-1] == "--" {
+			args = append(args, rest...)
+			return
+		}
+		if len(rest) == 0 {
+			return
+		}
+		args = append(args, rest[0])
+		argv = rest[1:]
+	}
+}
+
 func loadConfig() error {
-	configPath := filepath.Join(getConfigDir(), "config.json")
+	configPath := getConfigPath()
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return fmt.Errorf("missing config: %s\nCreate it with:\n{\n  \"client_id\": \"your-id\",\n  \"client_secret\": \"your-secret\"\n}", configPath)
 	}
-	return json.Unmarshal(data, &config)
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("invalid config %s: %v", configPath, err)
+	}
+	// Catch blank fields here rather than as a cryptic error from the
+	// token endpoint later.
+	for _, f := range []struct{ name, value string }{
+		{"client_id", config.ClientID},
+		{"client_secret", config.ClientSecret},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			return fmt.Errorf("%s is missing or empty in %s", f.name, configPath)
+		}
+	}
+	for _, pin := range config.PinSHA256 {
+		if sum, err := base64.StdEncoding.DecodeString(pin); err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("invalid pin_sha256 %q in %s: want a base64 SHA-256 hash", pin, configPath)
+		}
+	}
+	if config.Units != "" && config.Units != "metric" && config.Units != "imperial" {
+		return fmt.Errorf("invalid units %q in %s: want metric or imperial", config.Units, configPath)
+	}
+	if config.Timezone != "" {
+		loc, err := time.LoadLocation(config.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone %q in %s: want an IANA name, e.g. Europe/Berlin", config.Timezone, configPath)
+		}
+		time.Local = loc
+	}
+	return nil
 }
 
-type TokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int    `json:"expires_in"`
-	TokenType    string `json:"token_type"`
-}
+func main() {
+	flags.Usage = printUsage
+	parseArgs(os.Args[1:])
+	if *asciiFlag {
+		sym = asciiSymbols
+	}
 
-type StoredToken struct {
-	AccessToken  string    `json:"access_token"`
-	RefreshToken string    `json:"refresh_token"`
-	ExpiresAt    time.Time `json:"expires_at"`
-}
+	var stop context.CancelFunc
+	ctx, stop = signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		// Restore the default handler so a second Ctrl-C exits immediately.
+		<-ctx.Done()
+		stop()
+	}()
 
-func main() {
-	if len(os.Args) < 2 {
+	if len(args) < 1 {
 		printUsage()
 		os.Exit(1)
 	}
 
+	if *apiBaseFlag != "" {
+		apiBase = strings.TrimSuffix(*apiBaseFlag, "/")
+	}
+
+	if *proxyFlag != "" {
+		if u, err := url.Parse(*proxyFlag); err != nil || u.Scheme == "" || u.Host == "" {
+			fmt.Fprintf(os.Stderr, "invalid --proxy URL: %q\n", *proxyFlag)
+			os.Exit(1)
+		}
+	}
+
+	if *maxRetriesFlag < 0 {
+		fmt.Fprintf(os.Stderr, "invalid --max-retries %d: must be 0 or more\n", *maxRetriesFlag)
+		os.Exit(1)
+	}
+
+	if *profileFlag != "" && configOverride() != "" {
+		fmt.Fprintln(os.Stderr, "--profile cannot be combined with --config or OURA_CONFIG")
+		os.Exit(1)
+	}
+
+	if strings.ContainsAny(*profileFlag, `/\.`) {
+		fmt.Fprintf(os.Stderr, "invalid profile name: %q\n", *profileFlag)
+		os.Exit(1)
+	}
+
+	// These commands don't need a config, so they run before loadConfig.
+	switch args[0] {
+	case "doctor":
+		doDoctor()
+		return
+	case "version":
+		printVersion()
+		return
+	case "web":
+		args = args[1:]
+		doWeb()
+		return
+	case "config":
+		args = args[1:]
+		doConfig()
+		return
+	}
+
 	if err := loadConfig(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	if config.ASCII {
+		sym = asciiSymbols
+	}
+	client = NewClient(config)
+	client.Offline = *offlineFlag
+	client.RecordTimings = *timingsFlag
+	if *timingsFlag {
+		defer printTimings()
+	}
+
+	cmd := args[0]
+	args = args[1:]
+
+	if *forceRefreshFlag {
+		forceRefresh()
+		if cmd == "auth" {
+			return
+		}
+	}
+
+	if *onlyMissingFlag {
+		endpoint, ok := dailyEndpoints[cmd]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "--only-missing is not supported by %q\n", cmd)
+			os.Exit(1)
+		}
+		printMissingDays(endpoint)
+		return
+	}
+
+	if flagGiven("format") && tableCommands[cmd] && !tableFormat() {
+		fmt.Fprintf(os.Stderr, "unknown --format %q for %s (want table)\n", *formatFlag, cmd)
+		os.Exit(1)
+	}
+
+	if cmd == "heartrate" && *intervalFlag != 0 && !validInterval(*intervalFlag) {
+		fmt.Fprintf(os.Stderr, "invalid --interval %s: want whole minutes from 1m to 24h that divide a day (e.g. 15m, 1h)\n", *intervalFlag)
+		os.Exit(1)
+	}
+
+	if flag := singleValueFlag(); flag != "" {
+		printOnly(cmd, flag)
+		return
+	}
+
+	// Deferred, so it runs only if the command didn't exit with an error.
+	if config.OnFetch != "" && fetchCommands[cmd] && !*schemaFlag {
+		defer runFetchHook()
+	}
 
-	cmd := os.Args[1]
 	switch cmd {
 	case "auth":
-		doAuth()
+		if err := doAuth(); err != nil {
+			fatal(err)
+		}
 	case "today":
-		fetchAll(time.Now().Format("2006-01-02"))
+		fetchToday()
 	case "sleep":
-		fetchSleep(getDateArg())
+		if *regularityFlag {
+			fetchSleepRegularity()
+		} else if *nightsFlag > 0 {
+			fetchNights(*nightsFlag)
+		} else if rangeRequested() && len(args) == 0 {
+			fetchSleepRange()
+		} else {
+			forEachDay(fetchSleep)
+		}
 	case "activity":
-		fetchActivity(getDateArg())
+		if *streakFlag {
+			fetchActivityStreak()
+		} else {
+			forEachDay(fetchActivity)
+		}
 	case "readiness":
-		fetchReadiness(getDateArg())
+		forEachDay(fetchReadiness)
 	case "heartrate":
-		fetchHeartRate(getDateArg())
+		forEachDay(fetchHeartRate)
 	case "stress":
-		fetchStress(getDateArg())
+		forEachDay(fetchStress)
 	case "spo2":
-		fetchSpO2(getDateArg())
+		forEachDay(fetchSpO2)
 	case "resilience":
-		fetchResilience(getDateArg())
+		forEachDay(fetchResilience)
 	case "vo2":
-		fetchVO2Max(getDateArg())
+		forEachDay(fetchVO2Max)
 	case "workout":
-		fetchWorkouts(getDateArg())
+		var grand workoutTotals
+		forEachDay(func(date string) { grand.merge(fetchWorkouts(date)) })
+		if rangeRequested() && grand.Count > 0 {
+			fmt.Println()
+			grand.print("All days:")
+		}
 	case "all":
-		fetchAll(getDateArg())
+		forEachDay(fetchAll)
 	case "json":
 		fetchJSON(getDateArg())
+	case "summary":
+		fetchSummary(getDateArg())
+	case "raw":
+		doRaw()
+	case "rhr":
+		fetchRHRTrend()
+	case "report":
+		doReport()
+	case "watch":
+		doWatch()
+	case "export":
+		doExport()
+	case "notify":
+		doNotify()
+	case "stats":
+		doStats()
+	case "check":
+		doCheck()
 	default:
 		printUsage()
 		os.Exit(1)
 	}
 }
 
+// printTimings lists the API calls made, slowest first, on stderr so
+// the command's output stays clean.
+func printTimings() {
+	client.timingsMu.Lock()
+	timings := slices.Clone(client.Timings)
+	client.timingsMu.Unlock()
+	slices.SortStableFunc(timings, func(a, b Timing) int { return cmp.Compare(b.Duration, a.Duration) })
+
+	var total time.Duration
+	fmt.Fprintln(os.Stderr, "Timings:")
+	for _, t := range timings {
+		fmt.Fprintf(os.Stderr, "  %-24s %6dms\n", t.Endpoint, t.Duration.Milliseconds())
+		total += t.Duration
+	}
+	fmt.Fprintf(os.Stderr, "  %-24s %6dms (%s)\n", "total", total.Milliseconds(), plural(len(timings), "call"))
+}
+
 func printUsage() {
 	fmt.Println(`oura - Oura Ring CLI
 
@@ -110,142 +524,549 @@ Commands:
   spo2 [date]       Show blood oxygen data
   resilience [date] Show resilience data
   vo2 [date]        Show VO2 max data
-  workout [date]    Show workouts
+  workout [date]    Show workouts with daily totals
   json [date]       Raw JSON dump of all data
+  summary [date]    JSON summary of key metrics (or --from/--to for a range)
+  rhr               Lowest sleeping heart rate per day with trend (--from/--to,
+                    default: last 30 days)
+  stats METRIC      Mean, median, std dev, min and max over --from/--to
+  report            Markdown digest of --from/--to against the period before
+  export sqlite     Export daily metrics to SQLite (--from, --to, --db)
+  export csv        Export daily metrics to one CSV per table (--from, --to, --out)
+  export ical       Print workouts and sessions as an iCalendar feed (--from, --to)
+  raw /ENDPOINT     Pretty-print any API endpoint (--from/--to, --param key=value)
+  notify            Desktop notification with today's readiness and sleep
+  watch             Check a metric every --interval and alert past --below/--above
+  web [page]        Open the Oura web app (dashboard, sleep, activity, readiness, trends)
+  check             Exit non-zero if the ring hasn't synced within --max-age
+  config get|set    Read or change a config key (e.g. config set step_goal 12000)
+  doctor            Diagnose config, token, and connectivity problems
+  version           Show version and build info
+
+Flags:
+  --chart           Show contributors as bars and sleep stages as a stacked bar
+  --goal            Show calorie and step goal progress for activity
+  --hourly          Show activity intensity hour by hour
+  --streak          Show the current and longest streak of days meeting the step goal
+  --met-minutes     Show activity's high/medium/low time as one MET-minute total
+  --explain         Describe what each readiness and sleep contributor means
+  --hypnogram       Draw each sleep period's stages across the night
+  --hrv-chart       Sparkline each sleep period's overnight HRV
+  --regularity      With sleep and --from/--to, report how consistent bedtimes were
+  --by-weekday      With stats, show the mean and day count per weekday
+  --nights N        With sleep, table of the last N nights' scores and totals with the trend
+  --include-naps=false
+                    Leave naps out of sleep periods and sleep totals (main sleep only)
+  --compact         Show today/all as a compact two-column grid
+  --only LIST       With all/today, show only these sections (readiness,sleep,activity,stress,heartrate)
+  --skip LIST       With all/today, leave out these sections
+  --yesterday-fallback
+                    In today/all, show yesterday's value for metrics with no data yet
+  --baseline N      In today/all, compare scores with the previous N days (30d) or weeks (4w)
+  --rolling         Add 7- and 30-day rolling averages to summary
+  --profile NAME    Use a separate config and token (~/.config/oura/profiles/NAME)
+  --config FILE     Use this config file (also OURA_CONFIG); token and state live beside it
+  --verbose         Print diagnostic details (token refreshes, unexpected API responses)
+  --api-base URL    Override the API base URL (also OURA_API_BASE)
+  --offline         Serve data only from the local cache, never the network
+  --max-retries N   Retry transient network errors N times with backoff (default 3)
+  --concurrency N   Fetch a sleep range with up to N requests at once (default 4)
+  --qps N           Send at most N API requests per second, retries included (default 5, 0: no limit)
+  --timings         Print how long each API call took to stderr, slowest first
+  --proxy URL       Send requests through a proxy (default: HTTP_PROXY/HTTPS_PROXY)
+  --user-agent UA   User-Agent header for all requests (default: oura-cli/VERSION)
+  --scopes LIST     Comma-separated OAuth scopes to request in auth
+  --force-refresh   Refresh the token first (with auth: refresh only, no browser)
+  --dry-run         With auth, print the authorization and redirect URLs and exit
+  --precision N     Decimal places for averages, distances, SpO2, VO2 max and stats
+  --seconds         Show seconds in durations
+  --24h             Show times on a 24-hour clock (e.g. 23:15 instead of 11:15 PM)
+  --no-color        Disable colored output (also NO_COLOR)
+  --ascii           Plain ASCII output, no emoji or box drawing (also "ascii" in config)
+  --wide            Adapt charts and the compact grid to the terminal width
+  --width N         Lay out for exactly N columns (implies --wide)
+  --from DATE       Start date for ranges (export, summary, stats, daily commands)
+  --to DATE         End date for ranges (default: today)
+  --since N         Range of the last N days (7d) or weeks (2w), ending today
+  --until N         With --since, end the range N days or weeks ago
+  --only-missing    With a daily command and a range, print only the days with no data
+  --score-only      With sleep, activity or readiness, print only the day's score
+  --calories-only   With activity, print only the day's active calories
+  --steps-only      With activity, print only the day's steps
+  --db FILE         SQLite database for export (default: oura.db)
+  --out DIR         Directory for CSV export files (default: .)
+  --append          Add only new days to existing CSV files (SQLite always upserts)
+  --format FORMAT   Summary output: json (default) or jsonl, one line per day;
+                    daily views: table, each view's values as an aligned table
+  --template TMPL   Render each summary day with a Go template (e.g. '{{.Day}}: {{.SleepScore}}')
+  --group-by PERIOD With summary over a range, one object per week or month (averages, totals)
+  --schema          With summary, print the JSON Schema of its objects and exit
+  --anonymize       In json, summary and raw: blank ids/emails, dates as Day 1, Day 2, ...
+  --redact          In json and raw: blank id/email/timestamp (or redact_keys) values
+  --json-compact    Print json, summary and raw output as single-line JSON
+  --since-last      With export sqlite or csv, export from the day after the last
+                    synced one through today
+  --raw             List individual heart rate readings
+  --limit N         With --raw, show the first N readings (-N for the last N)
+  --every DURATION  With --raw, keep one reading per interval (e.g. 5m)
+  --param K=V       With raw, add a query parameter (repeatable)
+  --average MODE    Heart rate average: simple, or weighted to show both
+  --interval D      With heartrate, a min/avg/max table per interval (e.g. 1h);
+                    with watch, how often to check (default: 15m)
+  --stddev          With heartrate, also show the standard deviation of BPM
+  --resting         With heartrate, estimate resting HR (lowest 10-minute average)
+  --metric NAME     With watch, the metric to check (same names as stats)
+  --below N         With watch, alert when the metric is below N
+  --above N         With watch, alert when the metric is above N
+  --notify          With watch, also send a desktop notification (once per day)
+  --max-age D       With check, the oldest acceptable reading (default: 18h)
+  --source NAME     Only show workouts from this source (e.g. manual)
+  --activity NAME   Only show workouts of this activity (e.g. running)
+  --min-duration D  Only show workouts at least this long (e.g. 20m)
+
+Date format: YYYY-MM-DD, today or yesterday (defaults to today); daily
+commands accept several dates.
+Flags may come before or after the command; -- ends flag parsing.`)
+}
 
-Date format: YYYY-MM-DD (defaults to today)`)
+func printVersion() {
+	fmt.Printf("oura %s\n", version)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				fmt.Printf("commit:  %s\n", setting.Value)
+			}
+		}
+	}
+	fmt.Printf("go:      %s\n", runtime.Version())
+	fmt.Printf("os/arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
 }
 
+// getDateArg returns the date argument (YYYY-MM-DD, today or yesterday),
+// or today. A malformed date exits before any API call is made.
 func getDateArg() string {
-	if len(os.Args) > 2 {
-		return os.Args[2]
+	if len(args) > 0 {
+		date, err := resolveDate(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return date
 	}
 	return time.Now().Format("2006-01-02")
 }
 
-func getConfigDir() string {
-	home, _ := os.UserHomeDir()
-	dir := filepath.Join(home, ".config", "oura")
-	os.MkdirAll(dir, 0700)
-	return dir
+// resolveDate validates a date argument, turning today and yesterday into
+// dates.
+func resolveDate(s string) (string, error) {
+	switch s {
+	case "today":
+		return time.Now().Format("2006-01-02"), nil
+	case "yesterday":
+		return time.Now().AddDate(0, 0, -1).Format("2006-01-02"), nil
+	}
+	if _, err := parseDate(s); err != nil {
+		return "", err
+	}
+	return s, nil
 }
 
-func getTokenPath() string {
-	return filepath.Join(getConfigDir(), "token.json")
+// getDateArgs returns every date argument, validated, deduplicated and
+// sorted.
+func getDateArgs() []string {
+	var dates []string
+	for _, a := range args {
+		date, err := resolveDate(a)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		dates = append(dates, date)
+	}
+	slices.Sort(dates)
+	return slices.Compact(dates)
 }
 
-func saveToken(token *StoredToken) error {
-	data, err := json.MarshalIndent(token, "", "  ")
+// firstOuraYear is when Oura data could first exist; earlier dates are
+// most likely typos.
+const firstOuraYear = 2015
+
+// parseDate parses a YYYY-MM-DD date, with an error that shows the
+// offending input. A date after today (local time) is an error, since
+// it could only ever come back empty; one before firstOuraYear is
+// warned about.
+func parseDate(s string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02", s)
 	if err != nil {
-		return err
+		return time.Time{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD, e.g. %s", s, time.Now().Format("2006-01-02"))
 	}
-	return os.WriteFile(getTokenPath(), data, 0600)
+	if s > time.Now().Format("2006-01-02") {
+		return time.Time{}, fmt.Errorf("%s: that date is in the future", s)
+	}
+	if t.Year() < firstOuraYear {
+		fmt.Fprintf(os.Stderr, "warning: %s is before %d, when Oura data begins\n", s, firstOuraYear)
+	}
+	return t, nil
+}
+
+// parseRangeFlags returns the validated range from --from/--to, which
+// also take today and yesterday, or from --since/--until counted back
+// from today. --to defaults to today.
+func parseRangeFlags() (from, to string, err error) {
+	from, to = *fromFlag, *toFlag
+	if *sinceFlag != "" || *untilFlag != "" {
+		if from != "" || to != "" {
+			return "", "", fmt.Errorf("--since/--until cannot be combined with --from/--to")
+		}
+		if from, to, err = relativeRange(*sinceFlag, *untilFlag); err != nil {
+			return "", "", err
+		}
+	}
+	if from == "" && to != "" {
+		return "", "", fmt.Errorf("--to requires --from")
+	}
+	if from == "" {
+		return "", "", fmt.Errorf("--from is required")
+	}
+	if to == "" {
+		to = time.Now().Format("2006-01-02")
+	}
+	if from, err = resolveDate(from); err != nil {
+		return "", "", fmt.Errorf("--from: %v", err)
+	}
+	if to, err = resolveDate(to); err != nil {
+		return "", "", fmt.Errorf("--to: %v", err)
+	}
+	if to < from {
+		return "", "", fmt.Errorf("--to (%s) is before --from (%s)", to, from)
+	}
+	return from, to, nil
+}
+
+// relativeRange converts --since/--until lookbacks into dates. "--since 7d"
+// is the 7 days ending today; "--until 1w" ends the range a week ago.
+func relativeRange(since, until string) (from, to string, err error) {
+	if since == "" {
+		return "", "", fmt.Errorf("--until requires --since")
+	}
+	sinceDays, err := parseLookback(since)
+	if err != nil || sinceDays < 1 {
+		return "", "", fmt.Errorf("invalid --since %q: expected e.g. 7d or 2w", since)
+	}
+	untilDays := 0
+	if until != "" {
+		if untilDays, err = parseLookback(until); err != nil {
+			return "", "", fmt.Errorf("invalid --until %q: expected e.g. 1d or 1w", until)
+		}
+	}
+	today := time.Now()
+	return today.AddDate(0, 0, -(sinceDays - 1)).Format("2006-01-02"),
+		today.AddDate(0, 0, -untilDays).Format("2006-01-02"), nil
+}
+
+// parseLookback parses a number of days (7d) or weeks (2w).
+func parseLookback(s string) (int, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid lookback %q", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid lookback %q", s)
+	}
+	switch s[len(s)-1] {
+	case 'd':
+		return n, nil
+	case 'w':
+		return n * 7, nil
+	}
+	return 0, fmt.Errorf("invalid lookback %q", s)
 }
 
-func loadToken() (*StoredToken, error) {
-	data, err := os.ReadFile(getTokenPath())
+// rangeRequested reports whether a date range was given by flags. --to
+// alone counts, so parseRangeFlags can reject it instead of it being
+// ignored.
+func rangeRequested() bool {
+	return *fromFlag != "" || *toFlag != "" || *sinceFlag != "" || *untilFlag != ""
+}
+
+// forEachDay runs a single-day fetcher for the date argument, for each
+// of several date arguments, or for every day of a --from/--to or
+// --since range.
+func forEachDay(fetch func(date string)) {
+	if !rangeRequested() && len(args) > 1 {
+		for i, day := range getDateArgs() {
+			if i > 0 {
+				fmt.Printf("\n%s\n\n", rule(40))
+			}
+			fetch(day)
+		}
+		return
+	}
+	if !rangeRequested() {
+		fetch(getDateArg())
+		return
+	}
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "give either a date or a range, not both")
+		os.Exit(1)
+	}
+	from, to, err := parseRangeFlags()
 	if err != nil {
-		return nil, err
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	var token StoredToken
-	if err := json.Unmarshal(data, &token); err != nil {
-		return nil, err
+	for i, day := range daysBetween(from, to) {
+		if i > 0 {
+			fmt.Println()
+		}
+		fetch(day)
 	}
-	return &token, nil
 }
 
-func getValidToken() (string, error) {
-	token, err := loadToken()
+// dailyEndpoints maps commands to the endpoint --only-missing checks.
+var dailyEndpoints = map[string]string{
+	"sleep":      "/sleep",
+	"activity":   "/daily_activity",
+	"readiness":  "/daily_readiness",
+	"stress":     "/daily_stress",
+	"spo2":       "/daily_spo2",
+	"resilience": "/daily_resilience",
+	"vo2":        "/vO2_max",
+	"workout":    "/workout",
+}
+
+// printMissingDays prints each day in --from/--to that endpoint has no
+// record for, one per line.
+func printMissingDays(endpoint string) {
+	if !rangeRequested() {
+		fmt.Fprintln(os.Stderr, "--only-missing needs a range (--from/--to or --since)")
+		os.Exit(1)
+	}
+	from, to, err := parseRangeFlags()
 	if err != nil {
-		return "", fmt.Errorf("not authenticated - run 'oura auth' first")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	if time.Now().Add(5 * time.Minute).After(token.ExpiresAt) {
-		newToken, err := refreshToken(token.RefreshToken)
-		if err != nil {
-			return "", fmt.Errorf("token refresh failed - run 'oura auth' again: %v", err)
+	var data struct {
+		Data []struct {
+			Day string `json:"day"`
+		} `json:"data"`
+	}
+	if err := getRange(endpoint, from, to, &data); err != nil {
+		fatal(err)
+	}
+	present := map[string]bool{}
+	for _, r := range data.Data {
+		present[r.Day] = true
+	}
+	for _, day := range daysBetween(from, to) {
+		if !present[day] {
+			fmt.Println(day)
 		}
-		token = newToken
 	}
-
-	return token.AccessToken, nil
 }
 
-func refreshToken(refresh string) (*StoredToken, error) {
-	data := url.Values{}
-	data.Set("grant_type", "refresh_token")
-	data.Set("refresh_token", refresh)
-	data.Set("client_id", config.ClientID)
-	data.Set("client_secret", config.ClientSecret)
+// daysBetween lists every date from..to inclusive.
+func daysBetween(from, to string) []string {
+	start, _ := time.Parse("2006-01-02", from)
+	end, _ := time.Parse("2006-01-02", to)
+	var days []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, d.Format("2006-01-02"))
+	}
+	return days
+}
 
-	resp, err := http.PostForm(tokenURL, data)
-	if err != nil {
-		return nil, err
+// configOverride returns the config file from --config or OURA_CONFIG,
+// or "" for the default location.
+func configOverride() string {
+	if *configFlag != "" {
+		return *configFlag
 	}
-	defer resp.Body.Close()
+	return os.Getenv("OURA_CONFIG")
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("token refresh failed: %s", body)
+// getConfigDir returns the directory holding the config, token, cache and
+// state files: the overridden config file's directory, or ~/.config/oura
+// (or the profile's directory under it).
+func getConfigDir() string {
+	var dir string
+	if override := configOverride(); override != "" {
+		dir = filepath.Dir(override)
+	} else {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config", "oura")
+		if *profileFlag != "" {
+			dir = filepath.Join(dir, "profiles", *profileFlag)
+		}
 	}
+	os.MkdirAll(dir, 0700)
+	return dir
+}
 
-	var tokenResp TokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return nil, err
+func getConfigPath() string {
+	if override := configOverride(); override != "" {
+		return override
 	}
+	return filepath.Join(getConfigDir(), "config.json")
+}
 
-	stored := &StoredToken{
-		AccessToken:  tokenResp.AccessToken,
-		RefreshToken: tokenResp.RefreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+func getTokenPath() string {
+	return filepath.Join(getConfigDir(), "token.json")
+}
+
+func getCacheDir() string {
+	return filepath.Join(getConfigDir(), "cache")
+}
+
+// defaultScopes are requested when neither --scopes nor config sets any.
+var defaultScopes = []string{"daily", "heartrate", "personal", "workout", "spo2", "stress", "heart_health"}
+
+// knownScopes lists the scopes Oura's authorize endpoint accepts.
+var knownScopes = map[string]bool{
+	"email": true, "personal": true, "daily": true, "heartrate": true,
+	"workout": true, "tag": true, "session": true, "spo2": true,
+	"stress": true, "heart_health": true, "ring_configuration": true,
+}
+
+// authScopes returns the scopes from --scopes, then config, then the
+// defaults, warning about any Oura doesn't know.
+func authScopes() []string {
+	scopes := config.Scopes
+	if *scopesFlag != "" {
+		scopes = strings.FieldsFunc(*scopesFlag, func(r rune) bool { return r == ',' || r == ' ' })
 	}
-	if err := saveToken(stored); err != nil {
-		return nil, err
+	if len(scopes) == 0 {
+		return defaultScopes
+	}
+	for _, scope := range scopes {
+		if !knownScopes[scope] {
+			fmt.Fprintf(os.Stderr, "warning: unknown scope %q\n", scope)
+		}
 	}
+	return scopes
+}
 
-	return stored, nil
+// forceRefresh refreshes the token regardless of its expiry, e.g. before
+// a long export that could outlive the current token.
+func forceRefresh() {
+	token, err := client.LoadToken()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "not authenticated - run 'oura auth' first")
+		os.Exit(1)
+	}
+	token, err = client.Refresh(ctx, token.RefreshToken)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "Token refreshed, valid until %s\n", token.ExpiresAt.Local().Format("2006-01-02 15:04"))
 }
 
-func doAuth() {
+// doAuth runs the OAuth flow: it serves the callback on localhost, opens
+// the authorization page and exchanges the code it receives for a token.
+// Each call has its own mux, server and listener, all gone by the time it
+// returns.
+func doAuth() error {
+	if !strings.HasPrefix(callbackPath(), "/") {
+		return fmt.Errorf("callback_path must start with /: %q", config.CallbackPath)
+	}
+	successHTML := defaultSuccessHTML
+	if config.AuthSuccessHTML != "" {
+		data, err := os.ReadFile(config.AuthSuccessHTML)
+		if err != nil {
+			return fmt.Errorf("cannot read auth_success_html: %v", err)
+		}
+		successHTML = string(data)
+	}
+
 	state := fmt.Sprintf("%d", time.Now().UnixNano())
 
 	authParams := url.Values{}
 	authParams.Set("client_id", config.ClientID)
-	authParams.Set("redirect_uri", redirectURI)
+	authParams.Set("redirect_uri", redirectURI())
 	authParams.Set("response_type", "code")
-	authParams.Set("scope", "daily heartrate personal workout spo2 stress heart_health")
+	authParams.Set("scope", strings.Join(authScopes(), " "))
 	authParams.Set("state", state)
 
 	fullAuthURL := authURL + "?" + authParams.Encode()
 
+	if *dryRunFlag {
+		if config.ClientID == "" {
+			return fmt.Errorf("client_id is not set in config")
+		}
+		fmt.Println("Redirect URI:", redirectURI())
+		fmt.Println("Authorization URL:")
+		fmt.Println(fullAuthURL)
+		return nil
+	}
+
+	// Only the first result counts; later ones are dropped rather than
+	// blocking a handler.
 	codeChan := make(chan string, 1)
 	errChan := make(chan error, 1)
+	report := func(err error) {
+		select {
+		case errChan <- err:
+		default:
+		}
+	}
 
-	server := &http.Server{Addr: ":8081"}
+	// A mux of its own, so handlers never collide on the default one.
+	mux := http.NewServeMux()
+	server := &http.Server{Handler: mux}
 
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+	// Anything but the callback (e.g. the browser's favicon request) is a
+	// plain 404.
+	if callbackPath() != "/" {
+		mux.HandleFunc("/", http.NotFound)
+	}
+	mux.HandleFunc(callbackPath(), func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Query().Get("state") != state {
-			errChan <- fmt.Errorf("state mismatch")
+			report(fmt.Errorf("state mismatch"))
 			http.Error(w, "State mismatch", http.StatusBadRequest)
 			return
 		}
 
 		code := r.URL.Query().Get("code")
 		if code == "" {
-			errChan <- fmt.Errorf("no code in callback")
+			report(fmt.Errorf("no code in callback"))
 			http.Error(w, "No code", http.StatusBadRequest)
 			return
 		}
 
 		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprint(w, `<html><body><h1>✓ Authenticated!</h1><p>You can close this tab.</p></body></html>`)
-		codeChan <- code
+		fmt.Fprint(w, successHTML)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		select {
+		case codeChan <- code:
+		default:
+		}
 	})
 
+	// Listening up front reports a busy port here, not from a goroutine.
+	ln, err := net.Listen("tcp", ":8081")
+	if err != nil {
+		return fmt.Errorf("cannot listen for the auth callback: %w", err)
+	}
+	served := make(chan struct{})
 	go func() {
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			errChan <- err
+		defer close(served)
+		if err := server.Serve(ln); err != http.ErrServerClosed {
+			report(err)
+		}
+	}()
+	// Shutdown lets the response to the browser finish before the server
+	// goes, so the success page isn't cut off.
+	defer func() {
+		sctx, cancel := context.WithTimeout(context.Background(), authShutdownGrace)
+		defer cancel()
+		if server.Shutdown(sctx) != nil {
+			server.Close()
 		}
+		<-served
 	}()
 
 	fmt.Println("Opening browser for authentication...")
@@ -255,58 +1076,23 @@ func doAuth() {
 
 	select {
 	case code := <-codeChan:
-		server.Close()
-		exchangeCode(code)
+		return exchangeCode(code)
 	case err := <-errChan:
-		server.Close()
-		fmt.Fprintf(os.Stderr, "Auth error: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("auth error: %v", err)
 	case <-time.After(2 * time.Minute):
-		server.Close()
-		fmt.Fprintln(os.Stderr, "Auth timeout")
-		os.Exit(1)
+		return fmt.Errorf("auth timeout")
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-func exchangeCode(code string) {
-	data := url.Values{}
-	data.Set("grant_type", "authorization_code")
-	data.Set("code", code)
-	data.Set("redirect_uri", redirectURI)
-	data.Set("client_id", config.ClientID)
-	data.Set("client_secret", config.ClientSecret)
-
-	resp, err := http.PostForm(tokenURL, data)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Token exchange failed: %v\n", err)
-		os.Exit(1)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Fprintf(os.Stderr, "Token exchange failed: %s\n", body)
-		os.Exit(1)
-	}
-
-	var tokenResp TokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to parse token: %v\n", err)
-		os.Exit(1)
-	}
-
-	stored := &StoredToken{
-		AccessToken:  tokenResp.AccessToken,
-		RefreshToken: tokenResp.RefreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+func exchangeCode(code string) error {
+	if _, err := client.Exchange(ctx, code, redirectURI()); err != nil {
+		return fmt.Errorf("token exchange failed: %w", err)
 	}
 
-	if err := saveToken(stored); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to save token: %v\n", err)
-		os.Exit(1)
-	}
-
-	fmt.Println("✓ Authenticated successfully!")
+	fmt.Println(sym.Check, "Authenticated successfully!")
+	return nil
 }
 
 func openBrowser(url string) {
@@ -324,65 +1110,53 @@ func openBrowser(url string) {
 	}
 }
 
-func apiGet(endpoint string, params url.Values) ([]byte, error) {
-	token, err := getValidToken()
-	if err != nil {
-		return nil, err
+// decodeResponse unmarshals an API body. A body that isn't the expected
+// JSON (e.g. a gateway's HTML error page) is reported as an error rather
+// than silently decoding to no data.
+func decodeResponse(body []byte, v any) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		if *verboseFlag {
+			fmt.Fprintf(os.Stderr, "Response body:\n%s\n", body)
+		}
+		return fmt.Errorf("unexpected response from Oura API: %v", err)
 	}
+	return nil
+}
 
-	url := apiBase + endpoint
-	if len(params) > 0 {
-		url += "?" + params.Encode()
-	}
+// Data types
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
+type SleepResponse struct {
+	Data []SleepRecord `json:"data"`
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, body)
-	}
-
-	return body, nil
-}
-
-// Data types
-
-type SleepResponse struct {
-	Data []SleepRecord `json:"data"`
+type SleepRecord struct {
+	Day                string   `json:"day"`
+	Type               string   `json:"type"`
+	BedtimeStart       string   `json:"bedtime_start"`
+	BedtimeEnd         string   `json:"bedtime_end"`
+	TotalSleepDuration int      `json:"total_sleep_duration"`
+	TimeInBed          int      `json:"time_in_bed"`
+	Efficiency         *int     `json:"efficiency"`
+	DeepSleepDuration  int      `json:"deep_sleep_duration"`
+	LightSleepDuration int      `json:"light_sleep_duration"`
+	RemSleepDuration   int      `json:"rem_sleep_duration"`
+	AwakeTime          int      `json:"awake_time"`
+	Latency            *int     `json:"latency"`
+	LowestHeartRate    *int     `json:"lowest_heart_rate"`
+	AverageHeartRate   *float64 `json:"average_heart_rate"`
+	AverageHRV         *int     `json:"average_hrv"`
+	AverageBreath      *float64 `json:"average_breath"`
+	RestlessPeriods    *int     `json:"restless_periods"`
+	SleepPhase5Min     string   `json:"sleep_phase_5_min"`
+	HRV                *Samples `json:"hrv"`
 }
 
-type SleepRecord struct {
-	Day                string  `json:"day"`
-	Type               string  `json:"type"`
-	BedtimeStart       string  `json:"bedtime_start"`
-	BedtimeEnd         string  `json:"bedtime_end"`
-	TotalSleepDuration int     `json:"total_sleep_duration"`
-	TimeInBed          int     `json:"time_in_bed"`
-	Efficiency         int     `json:"efficiency"`
-	DeepSleepDuration  int     `json:"deep_sleep_duration"`
-	LightSleepDuration int     `json:"light_sleep_duration"`
-	RemSleepDuration   int     `json:"rem_sleep_duration"`
-	AwakeTime          int     `json:"awake_time"`
-	Latency            int     `json:"latency"`
-	LowestHeartRate    int     `json:"lowest_heart_rate"`
-	AverageHeartRate   float64 `json:"average_heart_rate"`
-	AverageHRV         int     `json:"average_hrv"`
-	AverageBreath      float64 `json:"average_breath"`
-	RestlessPeriods    int     `json:"restless_periods"`
+// Samples is a time series at a fixed interval (seconds) from Timestamp.
+// Items are null where the ring had no reading.
+type Samples struct {
+	Interval  float64    `json:"interval"`
+	Items     []*float64 `json:"items"`
+	Timestamp string     `json:"timestamp"`
 }
 
 type DailySleepResponse struct {
@@ -391,15 +1165,15 @@ type DailySleepResponse struct {
 
 type DailySleepRecord struct {
 	Day          string `json:"day"`
-	Score        int    `json:"score"`
+	Score        *int   `json:"score"`
 	Contributors struct {
-		DeepSleep   int `json:"deep_sleep"`
-		Efficiency  int `json:"efficiency"`
-		Latency     int `json:"latency"`
-		RemSleep    int `json:"rem_sleep"`
-		Restfulness int `json:"restfulness"`
-		Timing      int `json:"timing"`
-		TotalSleep  int `json:"total_sleep"`
+		DeepSleep   *int `json:"deep_sleep"`
+		Efficiency  *int `json:"efficiency"`
+		Latency     *int `json:"latency"`
+		RemSleep    *int `json:"rem_sleep"`
+		Restfulness *int `json:"restfulness"`
+		Timing      *int `json:"timing"`
+		TotalSleep  *int `json:"total_sleep"`
 	} `json:"contributors"`
 }
 
@@ -408,18 +1182,18 @@ type ReadinessResponse struct {
 }
 
 type ReadinessRecord struct {
-	Day                       string  `json:"day"`
-	Score                     int     `json:"score"`
-	TemperatureDeviation      float64 `json:"temperature_deviation"`
+	Day                       string   `json:"day"`
+	Score                     *int     `json:"score"`
+	TemperatureDeviation      *float64 `json:"temperature_deviation"`
 	TemperatureTrendDeviation *float64 `json:"temperature_trend_deviation"`
 	Contributors              struct {
-		ActivityBalance     int  `json:"activity_balance"`
-		BodyTemperature     int  `json:"body_temperature"`
+		ActivityBalance     *int `json:"activity_balance"`
+		BodyTemperature     *int `json:"body_temperature"`
 		HRVBalance          *int `json:"hrv_balance"`
-		PreviousDayActivity int  `json:"previous_day_activity"`
-		PreviousNight       int  `json:"previous_night"`
-		RecoveryIndex       int  `json:"recovery_index"`
-		RestingHeartRate    int  `json:"resting_heart_rate"`
+		PreviousDayActivity *int `json:"previous_day_activity"`
+		PreviousNight       *int `json:"previous_night"`
+		RecoveryIndex       *int `json:"recovery_index"`
+		RestingHeartRate    *int `json:"resting_heart_rate"`
 		SleepBalance        *int `json:"sleep_balance"`
 		SleepRegularity     *int `json:"sleep_regularity"`
 	} `json:"contributors"`
@@ -431,7 +1205,7 @@ type ActivityResponse struct {
 
 type ActivityRecord struct {
 	Day                   string `json:"day"`
-	Score                 int    `json:"score"`
+	Score                 *int   `json:"score"`
 	Steps                 int    `json:"steps"`
 	ActiveCalories        int    `json:"active_calories"`
 	TotalCalories         int    `json:"total_calories"`
@@ -442,6 +1216,15 @@ type ActivityRecord struct {
 	LowActivityTime       int    `json:"low_activity_time"`
 	SedentaryTime         int    `json:"sedentary_time"`
 	RestingTime           int    `json:"resting_time"`
+
+	// Class5Min has one digit per 5 minutes of the activity day:
+	// 0 non-wear, 1 rest, 2 inactive, 3 low, 4 medium, 5 high.
+	Class5Min string `json:"class_5_min"`
+	MET       *struct {
+		Interval  float64   `json:"interval"`
+		Items     []float64 `json:"items"`
+		Timestamp string    `json:"timestamp"`
+	} `json:"met"`
 }
 
 type HeartRateResponse struct {
@@ -458,11 +1241,15 @@ type StressResponse struct {
 	Data []StressRecord `json:"data"`
 }
 
+// StressRecord is a day's daytime stress. StressHigh and RecoveryHigh
+// are seconds; DaySummary is restored, normal or stressful, and null
+// until the day has enough data. The API sends no timezone or offset:
+// Day is already the wearer's local day, so there is none to show.
 type StressRecord struct {
-	Day             string  `json:"day"`
-	StressHigh      int     `json:"stress_high"`
-	RecoveryHigh    int     `json:"recovery_high"`
-	DaytimeStress   float64 `json:"day_summary"`
+	Day          string  `json:"day"`
+	StressHigh   *int    `json:"stress_high"`
+	RecoveryHigh *int    `json:"recovery_high"`
+	DaySummary   *string `json:"day_summary"`
 }
 
 type SpO2Response struct {
@@ -470,11 +1257,11 @@ type SpO2Response struct {
 }
 
 type SpO2Record struct {
-	Day                string `json:"day"`
-	SpO2Percentage     struct {
+	Day            string `json:"day"`
+	SpO2Percentage *struct {
 		Average float64 `json:"average"`
 	} `json:"spo2_percentage"`
-	BreathingDisturbanceIndex float64 `json:"breathing_disturbance_index"`
+	BreathingDisturbanceIndex *float64 `json:"breathing_disturbance_index"`
 }
 
 type ResilienceResponse struct {
@@ -485,8 +1272,10 @@ type ResilienceRecord struct {
 	Day          string `json:"day"`
 	Level        string `json:"level"`
 	Contributors struct {
-		SleepRecovery    float64 `json:"sleep_recovery"`
-		DaytimeRecovery  float64 `json:"daytime_recovery"`
+		// Each contributor is a 0-100 score, not a fraction.
+		SleepRecovery   float64 `json:"sleep_recovery"`
+		DaytimeRecovery float64 `json:"daytime_recovery"`
+		Stress          float64 `json:"stress"`
 	} `json:"contributors"`
 }
 
@@ -495,8 +1284,8 @@ type VO2MaxResponse struct {
 }
 
 type VO2MaxRecord struct {
-	Day      string  `json:"day"`
-	VO2Max   float64 `json:"vo2_max"`
+	Day    string   `json:"day"`
+	VO2Max *float64 `json:"vo2_max"`
 }
 
 type WorkoutResponse struct {
@@ -504,6 +1293,7 @@ type WorkoutResponse struct {
 }
 
 type WorkoutRecord struct {
+	ID            string  `json:"id"`
 	Day           string  `json:"day"`
 	Activity      string  `json:"activity"`
 	Calories      float64 `json:"calories"`
@@ -517,422 +1307,1543 @@ type WorkoutRecord struct {
 
 // Fetch functions
 
-func fetchSleep(date string) {
+// dayWindow returns query params spanning the day before and after date,
+// since records near midnight can land on an adjacent day.
+func dayWindow(date string) url.Values {
 	targetDate, _ := time.Parse("2006-01-02", date)
 	startDate := targetDate.AddDate(0, 0, -1).Format("2006-01-02")
 	endDate := targetDate.AddDate(0, 0, 1).Format("2006-01-02")
-	
+
 	params := url.Values{}
 	params.Set("start_date", startDate)
 	params.Set("end_date", endDate)
+	return params
+}
 
-	// Try daily_sleep first for the score
-	dailyBody, dailyErr := apiGet("/daily_sleep", params)
-	var dailyData DailySleepResponse
-	var dailySleep *DailySleepRecord
-	if dailyErr == nil {
-		json.Unmarshal(dailyBody, &dailyData)
-		for i := range dailyData.Data {
-			if dailyData.Data[i].Day == date {
-				dailySleep = &dailyData.Data[i]
-				break
-			}
+// singleDay returns query params for exactly one day.
+func singleDay(date string) url.Values {
+	return rangeParams(date, date)
+}
+
+// rangeParams returns query params for the inclusive date range.
+func rangeParams(from, to string) url.Values {
+	params := url.Values{}
+	params.Set("start_date", from)
+	params.Set("end_date", to)
+	return params
+}
+
+// endpointVariants lists other names an endpoint has been published
+// under. Oura has been inconsistent about VO2 max's casing.
+var endpointVariants = map[string][]string{
+	"/vO2_max": {"/vo2_max", "/daily_vO2_max"},
+}
+
+// getEndpoint is client.Get for an endpoint that may have variants. On a
+// 404 it tries each variant, and remembers the one that answered in
+// endpoints.json so later runs go straight to it.
+func getEndpoint(endpoint string, params url.Values) ([]byte, error) {
+	variants, ok := endpointVariants[endpoint]
+	if !ok {
+		return client.Get(ctx, endpoint, params)
+	}
+	saved := loadEndpoints()
+	candidates := []string{endpoint}
+	if working := saved[endpoint]; working != "" && working != endpoint {
+		candidates = []string{working, endpoint}
+	}
+	for _, v := range variants {
+		if !slices.Contains(candidates, v) {
+			candidates = append(candidates, v)
 		}
 	}
 
-	// Get detailed sleep periods
-	body, err := apiGet("/sleep", params)
+	var (
+		body   []byte
+		err    error
+		name   string
+		apiErr *APIError
+	)
+	for _, name = range candidates {
+		body, err = client.Get(ctx, name, params)
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+			break
+		}
+	}
+	if err == nil && saved[endpoint] != name {
+		if *verboseFlag {
+			fmt.Fprintf(os.Stderr, "%s answered for %s\n", name, endpoint)
+		}
+		saved[endpoint] = name
+		saveEndpoints(saved)
+	}
+	return body, err
+}
+
+func getEndpointsPath() string {
+	return filepath.Join(getConfigDir(), "endpoints.json")
+}
+
+func loadEndpoints() map[string]string {
+	saved := map[string]string{}
+	if data, err := os.ReadFile(getEndpointsPath()); err == nil {
+		json.Unmarshal(data, &saved)
+	}
+	return saved
+}
+
+// saveEndpoints is best effort: failing only costs a probe next time.
+func saveEndpoints(saved map[string]string) {
+	data, _ := json.MarshalIndent(saved, "", "  ")
+	os.WriteFile(getEndpointsPath(), data, 0600)
+}
+
+// getRange fetches an endpoint over the inclusive date range into v.
+// The API may include neighbouring days, so callers filter by day.
+func getRange(endpoint, from, to string, v any) error {
+	body, err := getEndpoint(endpoint, rangeParams(from, to))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return err
 	}
+	return decodeResponse(body, v)
+}
 
-	var data SleepResponse
-	json.Unmarshal(body, &data)
+func getDailySleep(date string) (*DailySleepRecord, error) {
+	body, err := client.Get(ctx, "/daily_sleep", dayWindow(date))
+	if err != nil {
+		return nil, err
+	}
+
+	var data DailySleepResponse
+	if err := decodeResponse(body, &data); err != nil {
+		return nil, err
+	}
 
-	// Collect all sleep records for this date
-	var sleepRecords []SleepRecord
 	for i := range data.Data {
 		if data.Data[i].Day == date {
-			sleepRecords = append(sleepRecords, data.Data[i])
+			return &data.Data[i], nil
 		}
 	}
-	
-	if len(sleepRecords) == 0 && dailySleep == nil {
-		fmt.Println("No sleep data for", date)
-		return
+	return nil, nil
+}
+
+func getSleepPeriods(date string) ([]SleepRecord, error) {
+	body, err := client.Get(ctx, "/sleep", dayWindow(date))
+	if err != nil {
+		return nil, err
 	}
-	
-	fmt.Printf("🌙 Sleep - %s\n", date)
-	fmt.Println(strings.Repeat("─", 40))
 
-	if dailySleep != nil {
-		fmt.Printf("Score:         %d\n", dailySleep.Score)
-		fmt.Println()
-		fmt.Println("Contributors:")
-		fmt.Printf("  Total Sleep:   %d\n", dailySleep.Contributors.TotalSleep)
-		fmt.Printf("  Efficiency:    %d\n", dailySleep.Contributors.Efficiency)
-		fmt.Printf("  Restfulness:   %d\n", dailySleep.Contributors.Restfulness)
-		fmt.Printf("  REM Sleep:     %d\n", dailySleep.Contributors.RemSleep)
-		fmt.Printf("  Deep Sleep:    %d\n", dailySleep.Contributors.DeepSleep)
-		fmt.Printf("  Latency:       %d\n", dailySleep.Contributors.Latency)
-		fmt.Printf("  Timing:        %d\n", dailySleep.Contributors.Timing)
-		fmt.Println()
+	var data SleepResponse
+	if err := decodeResponse(body, &data); err != nil {
+		return nil, err
 	}
 
-	for i, s := range sleepRecords {
-		bedStart, _ := time.Parse(time.RFC3339, s.BedtimeStart)
-		bedEnd, _ := time.Parse(time.RFC3339, s.BedtimeEnd)
-		bedStart = bedStart.Local()
-		bedEnd = bedEnd.Local()
-		
-		// Label the sleep type
-		sleepLabel := "😴 Nap"
-		if s.Type == "long_sleep" {
-			sleepLabel = "🛏️  Main Sleep"
-		}
-		
-		if i > 0 {
-			fmt.Println()
-			fmt.Println(strings.Repeat("─", 40))
+	// Collect all sleep records for this date
+	var sleepRecords []SleepRecord
+	for i := range data.Data {
+		if data.Data[i].Day == date && countsSleep(data.Data[i]) {
+			sleepRecords = append(sleepRecords, data.Data[i])
 		}
-		fmt.Printf("%s\n", sleepLabel)
-		fmt.Printf("Time:          %s → %s\n", bedStart.Format("3:04 PM"), bedEnd.Format("3:04 PM"))
-		fmt.Printf("Total Sleep:   %s\n", formatDuration(s.TotalSleepDuration))
-		fmt.Printf("Time in Bed:   %s\n", formatDuration(s.TimeInBed))
-		fmt.Printf("Efficiency:    %d%%\n", s.Efficiency)
-		fmt.Println()
-		fmt.Printf("Deep Sleep:    %s\n", formatDuration(s.DeepSleepDuration))
-		fmt.Printf("Light Sleep:   %s\n", formatDuration(s.LightSleepDuration))
-		fmt.Printf("REM Sleep:     %s\n", formatDuration(s.RemSleepDuration))
-		fmt.Printf("Awake:         %s\n", formatDuration(s.AwakeTime))
-		fmt.Printf("Latency:       %s\n", formatDuration(s.Latency))
-		fmt.Println()
-		fmt.Printf("Lowest HR:     %d bpm\n", s.LowestHeartRate)
-		fmt.Printf("Average HR:    %.0f bpm\n", s.AverageHeartRate)
-		fmt.Printf("Average HRV:   %d ms\n", s.AverageHRV)
-		fmt.Printf("Breath Rate:   %.1f /min\n", s.AverageBreath)
-		fmt.Printf("Restlessness:  %d periods\n", s.RestlessPeriods)
 	}
+	sortByTime(sleepRecords, func(s SleepRecord) string { return s.BedtimeStart })
+	return sleepRecords, nil
 }
 
-func fetchReadiness(date string) {
-	targetDate, _ := time.Parse("2006-01-02", date)
-	startDate := targetDate.AddDate(0, 0, -1).Format("2006-01-02")
-	endDate := targetDate.AddDate(0, 0, 1).Format("2006-01-02")
-	
-	params := url.Values{}
-	params.Set("start_date", startDate)
-	params.Set("end_date", endDate)
+// countsSleep reports whether a sleep period is shown and counted:
+// everything by default, only the main sleep with --include-naps=false.
+func countsSleep(s SleepRecord) bool {
+	return *includeNapsFlag || s.Type == "long_sleep"
+}
+
+// sleepScope is a header note for output that leaves naps out.
+func sleepScope() string {
+	if *includeNapsFlag {
+		return ""
+	}
+	return " (main sleep only)"
+}
 
-	body, err := apiGet("/daily_readiness", params)
+func getReadiness(date string) (*ReadinessRecord, error) {
+	body, err := client.Get(ctx, "/daily_readiness", dayWindow(date))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
 	var data ReadinessResponse
-	json.Unmarshal(body, &data)
+	if err := decodeResponse(body, &data); err != nil {
+		return nil, err
+	}
 
-	var r *ReadinessRecord
 	for i := range data.Data {
 		if data.Data[i].Day == date {
-			r = &data.Data[i]
-			break
+			return &data.Data[i], nil
 		}
 	}
-	
-	if r == nil {
-		fmt.Println("No readiness data for", date)
-		return
-	}
-
-	c := r.Contributors
-
-	fmt.Printf("💪 Readiness - %s\n", r.Day)
-	fmt.Println(strings.Repeat("─", 40))
-	fmt.Printf("Score:              %d\n", r.Score)
-	fmt.Printf("Temp Deviation:     %+.2f°C\n", r.TemperatureDeviation)
-	fmt.Println()
-	fmt.Println("Contributors:")
-	fmt.Printf("  Resting HR:       %d\n", c.RestingHeartRate)
-	if c.HRVBalance != nil {
-		fmt.Printf("  HRV Balance:      %d\n", *c.HRVBalance)
-	}
-	fmt.Printf("  Body Temp:        %d\n", c.BodyTemperature)
-	fmt.Printf("  Recovery Index:   %d\n", c.RecoveryIndex)
-	fmt.Printf("  Previous Night:   %d\n", c.PreviousNight)
-	fmt.Printf("  Prev Day Activity:%d\n", c.PreviousDayActivity)
-	fmt.Printf("  Activity Balance: %d\n", c.ActivityBalance)
-	if c.SleepBalance != nil {
-		fmt.Printf("  Sleep Balance:    %d\n", *c.SleepBalance)
-	}
-	if c.SleepRegularity != nil {
-		fmt.Printf("  Sleep Regularity: %d\n", *c.SleepRegularity)
-	}
+	return nil, nil
 }
 
-func fetchActivity(date string) {
-	targetDate, _ := time.Parse("2006-01-02", date)
-	startDate := targetDate.AddDate(0, 0, -1).Format("2006-01-02")
-	endDate := targetDate.AddDate(0, 0, 1).Format("2006-01-02")
-	
-	params := url.Values{}
-	params.Set("start_date", startDate)
-	params.Set("end_date", endDate)
-
-	body, err := apiGet("/daily_activity", params)
+func getActivity(date string) (*ActivityRecord, error) {
+	body, err := client.Get(ctx, "/daily_activity", dayWindow(date))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
 	var data ActivityResponse
-	json.Unmarshal(body, &data)
+	if err := decodeResponse(body, &data); err != nil {
+		return nil, err
+	}
 
-	var a *ActivityRecord
 	for i := range data.Data {
 		if data.Data[i].Day == date {
-			a = &data.Data[i]
-			break
+			return &data.Data[i], nil
 		}
 	}
-	
-	if a == nil {
-		fmt.Println("No activity data for", date)
-		return
-	}
-	
-	fmt.Printf("🏃 Activity - %s\n", a.Day)
-	fmt.Println(strings.Repeat("─", 40))
-	fmt.Printf("Score:         %d\n", a.Score)
-	fmt.Printf("Steps:         %d\n", a.Steps)
-	fmt.Printf("Distance:      %.1f km\n", float64(a.EquivalentWalkingDist)/1000)
-	fmt.Println()
-	fmt.Printf("Active Cal:    %d\n", a.ActiveCalories)
-	fmt.Printf("Total Cal:     %d\n", a.TotalCalories)
-	fmt.Printf("Target Cal:    %d\n", a.TargetCalories)
-	fmt.Println()
-	fmt.Printf("High Activity: %s\n", formatDuration(a.HighActivityTime))
-	fmt.Printf("Med Activity:  %s\n", formatDuration(a.MediumActivityTime))
-	fmt.Printf("Low Activity:  %s\n", formatDuration(a.LowActivityTime))
-	fmt.Printf("Sedentary:     %s\n", formatDuration(a.SedentaryTime))
-	fmt.Printf("Resting:       %s\n", formatDuration(a.RestingTime))
+	return nil, nil
 }
 
-func fetchHeartRate(date string) {
-	params := url.Values{}
-	params.Set("start_date", date)
-	params.Set("end_date", date)
-
-	body, err := apiGet("/heartrate", params)
+func getHeartRate(date string) ([]HeartRateRecord, error) {
+	body, err := client.Get(ctx, "/heartrate", singleDay(date))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
 	var data HeartRateResponse
-	json.Unmarshal(body, &data)
-
-	if len(data.Data) == 0 {
-		fmt.Println("No heart rate data for", date)
-		return
-	}
-
-	var min, max, sum int
-	min = 999
-	for _, hr := range data.Data {
-		if hr.BPM < min {
-			min = hr.BPM
-		}
-		if hr.BPM > max {
-			max = hr.BPM
-		}
-		sum += hr.BPM
+	if err := decodeResponse(body, &data); err != nil {
+		return nil, err
 	}
-	avg := sum / len(data.Data)
-
-	fmt.Printf("❤️  Heart Rate - %s\n", date)
-	fmt.Println(strings.Repeat("─", 40))
-	fmt.Printf("Readings:  %d\n", len(data.Data))
-	fmt.Printf("Min:       %d bpm\n", min)
-	fmt.Printf("Max:       %d bpm\n", max)
-	fmt.Printf("Average:   %d bpm\n", avg)
+	sortByTime(data.Data, func(hr HeartRateRecord) string { return hr.Timestamp })
+	return data.Data, nil
 }
 
-func fetchStress(date string) {
-	params := url.Values{}
-	params.Set("start_date", date)
-	params.Set("end_date", date)
+// sortByTime orders records by an RFC 3339 timestamp. The API doesn't
+// guarantee order, and output should be chronological and deterministic.
+func sortByTime[T any](records []T, timestamp func(T) string) {
+	slices.SortStableFunc(records, func(a, b T) int {
+		ta, _ := time.Parse(time.RFC3339, timestamp(a))
+		tb, _ := time.Parse(time.RFC3339, timestamp(b))
+		return ta.Compare(tb)
+	})
+}
 
-	body, err := apiGet("/daily_stress", params)
+func getStress(date string) (*StressRecord, error) {
+	body, err := client.Get(ctx, "/daily_stress", singleDay(date))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
 	var data StressResponse
-	json.Unmarshal(body, &data)
+	if err := decodeResponse(body, &data); err != nil {
+		return nil, err
+	}
 
-	if len(data.Data) == 0 {
-		fmt.Println("No stress data for", date)
-		return
+	for i := range data.Data {
+		if data.Data[i].Day == date {
+			return &data.Data[i], nil
+		}
 	}
+	return nil, nil
+}
 
-	s := data.Data[0]
+// fatal prints an API error and exits.
+func fatal(err error) {
+	if errors.Is(err, context.Canceled) {
+		exitCancelled()
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
 
-	fmt.Printf("😤 Stress - %s\n", s.Day)
-	fmt.Println(strings.Repeat("─", 40))
-	fmt.Printf("Stress High:     %d min\n", s.StressHigh)
-	fmt.Printf("Recovery High:   %d min\n", s.RecoveryHigh)
+// exitCancelled exits with the conventional status for SIGINT.
+func exitCancelled() {
+	fmt.Fprintln(os.Stderr, "cancelled")
+	os.Exit(130)
 }
 
-func fetchSpO2(date string) {
-	params := url.Values{}
-	params.Set("start_date", date)
-	params.Set("end_date", date)
+func fetchSleep(date string) {
+	// Try daily_sleep first for the score
+	dailySleep, _ := getDailySleep(date)
 
-	body, err := apiGet("/daily_spo2", params)
+	// Get detailed sleep periods
+	sleepRecords, err := getSleepPeriods(date)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		fatal(err)
 	}
+	printSleep(date, dailySleep, sleepRecords)
+}
 
-	var data SpO2Response
-	json.Unmarshal(body, &data)
+// sleepWidth is the label column of the sleep view.
+const sleepWidth = 15
 
-	if len(data.Data) == 0 {
-		fmt.Println("No SpO2 data for", date)
+func printSleep(date string, dailySleep *DailySleepRecord, sleepRecords []SleepRecord) {
+	if len(sleepRecords) == 0 && dailySleep == nil {
+		fmt.Println("No sleep data for", date)
 		return
 	}
 
-	s := data.Data[0]
-
-	fmt.Printf("🫁 Blood Oxygen - %s\n", s.Day)
-	fmt.Println(strings.Repeat("─", 40))
-	fmt.Printf("Average SpO2:    %.1f%%\n", s.SpO2Percentage.Average)
-	fmt.Printf("Breathing Index: %.2f\n", s.BreathingDisturbanceIndex)
-}
+	day := ""
+	if dailySleep != nil {
+		day = dailySleep.Day
+	} else {
+		day = sleepRecords[0].Day
+	}
+	fmt.Printf("%sSleep - %s%s\n", sym.Sleep, headerDay(date, day), sleepScope())
+	fmt.Println(rule(40))
 
-func fetchResilience(date string) {
-	params := url.Values{}
-	params.Set("start_date", date)
-	params.Set("end_date", date)
+	var v rowView
+	if dailySleep != nil {
+		c := dailySleep.Contributors
+		v.add(sleepWidth, kv{"Score", optInt(dailySleep.Score, "")}, kv{})
+		v.line("Contributors:", kv{Key: "Contributors"})
+		v.contributors([]contributor{
+			{"Total Sleep", "total_sleep", c.TotalSleep},
+			{"Efficiency", "efficiency", c.Efficiency},
+			{"Restfulness", "restfulness", c.Restfulness},
+			{"REM Sleep", "rem_sleep", c.RemSleep},
+			{"Deep Sleep", "deep_sleep", c.DeepSleep},
+			{"Latency", "latency", c.Latency},
+			{"Timing", "timing", c.Timing},
+		}, sleepWidth)
+		v.add(sleepWidth, kv{})
+	}
 
-	body, err := apiGet("/daily_resilience", params)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if config.SleepGoalHours > 0 && len(sleepRecords) > 0 {
+		total := 0
+		for _, s := range sleepRecords {
+			total += s.TotalSleepDuration
+		}
+		v.add(sleepWidth, kv{"Total Sleep", formatDuration(total) + sleepGoalNote(total)}, kv{})
 	}
 
-	var data ResilienceResponse
-	json.Unmarshal(body, &data)
+	for i, s := range sleepRecords {
+		bedStart, _ := time.Parse(time.RFC3339, s.BedtimeStart)
+		bedEnd, _ := time.Parse(time.RFC3339, s.BedtimeEnd)
+		bedStart = bedStart.Local()
+		bedEnd = bedEnd.Local()
 
-	if len(data.Data) == 0 {
-		fmt.Println("No resilience data for", date)
-		return
+		sleepLabel := sleepTypeLabel(s.Type)
+
+		if i > 0 {
+			v.add(sleepWidth, kv{})
+			v.line(rule(40), kv{})
+		}
+		v.line(sleepLabel, kv{Key: sleepLabel})
+		efficiency := optInt(s.Efficiency, "%")
+		if s.Efficiency != nil && *s.Efficiency < config.MinSleepEfficiency {
+			efficiency += colorize(colorYellow, fmt.Sprintf(" (below %d%%)", config.MinSleepEfficiency))
+		}
+		latency := na
+		if s.Latency != nil {
+			latency = formatDuration(*s.Latency)
+		}
+		v.add(sleepWidth,
+			kv{"Time", bedStart.Format(clockLayout()) + " " + sym.Arrow + " " + bedEnd.Format(clockLayout())},
+			kv{"Total Sleep", formatDuration(s.TotalSleepDuration)},
+			kv{"Time in Bed", formatDuration(s.TimeInBed)},
+			kv{"Efficiency", efficiency},
+			kv{},
+			kv{"Deep Sleep", formatDuration(s.DeepSleepDuration)},
+			kv{"Light Sleep", formatDuration(s.LightSleepDuration)},
+			kv{"REM Sleep", formatDuration(s.RemSleepDuration)},
+			kv{"Awake", formatDuration(s.AwakeTime)},
+			kv{"Latency", latency},
+		)
+		if *chartFlag {
+			v.add(sleepWidth, kv{})
+			v.add(sleepWidth, stageChart(s, sleepWidth)...)
+		}
+		if *hypnogramFlag {
+			v.add(sleepWidth, kv{})
+			v.add(sleepWidth, hypnogram(s, sleepWidth)...)
+		}
+		v.add(sleepWidth,
+			kv{},
+			kv{"Lowest HR", optInt(s.LowestHeartRate, " bpm")},
+			kv{"Average HR", optFloat(s.AverageHeartRate, 0, " bpm")},
+			kv{"Average HRV", optInt(s.AverageHRV, " ms")},
+		)
+		if *hrvChartFlag {
+			v.add(sleepWidth, hrvChart(s, sleepWidth)...)
+		}
+		v.add(sleepWidth,
+			kv{"Breath Rate", optFloat(s.AverageBreath, 1, " /min")},
+			kv{"Restlessness", optInt(s.RestlessPeriods, " periods")},
+		)
 	}
+	v.print()
+}
 
-	r := data.Data[0]
+// sleepGoalNote compares a day's total sleep with sleep_goal_hours,
+// e.g. " (goal 8h, -48m)".
+func sleepGoalNote(total int) string {
+	goal := int(config.SleepGoalHours * 3600)
+	hours := strconv.FormatFloat(config.SleepGoalHours, 'f', -1, 64) + "h"
+	if total >= goal {
+		return colorize(colorGreen, fmt.Sprintf(" (goal %s, +%s)", hours, formatDuration(total-goal)))
+	}
+	return colorize(colorYellow, fmt.Sprintf(" (goal %s, -%s)", hours, formatDuration(goal-total)))
+}
 
-	fmt.Printf("🛡️  Resilience - %s\n", r.Day)
-	fmt.Println(strings.Repeat("─", 40))
-	fmt.Printf("Level:            %s\n", r.Level)
-	fmt.Printf("Sleep Recovery:   %.0f%%\n", r.Contributors.SleepRecovery*100)
-	fmt.Printf("Daytime Recovery: %.0f%%\n", r.Contributors.DaytimeRecovery*100)
+// headerDay is the date a view's header shows: the record's own day,
+// noting the requested date when the API returned a different one.
+// requested may carry a note, like --yesterday-fallback's "(yesterday)".
+func headerDay(requested, day string) string {
+	if day == "" || strings.HasPrefix(requested, day) {
+		return requested
+	}
+	return fmt.Sprintf("%s (requested %s)", day, requested)
 }
 
-func fetchVO2Max(date string) {
-	params := url.Values{}
-	params.Set("start_date", date)
-	params.Set("end_date", date)
+// sleepTypeLabel returns the label for a sleep type, falling back to the
+// raw type so unknown values aren't mislabeled.
+func sleepTypeLabel(sleepType string) string {
+	if label, ok := sym.SleepTypes[sleepType]; ok {
+		return label
+	}
+	return sym.OtherSleep + sleepType
+}
 
-	body, err := apiGet("/vO2_max", params)
+func fetchReadiness(date string) {
+	r, err := getReadiness(date)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		fatal(err)
 	}
+	printReadiness(date, r)
+}
 
-	var data VO2MaxResponse
-	json.Unmarshal(body, &data)
-
-	if len(data.Data) == 0 {
-		fmt.Println("No VO2 max data for", date)
+func printReadiness(date string, r *ReadinessRecord) {
+	if r == nil {
+		fmt.Println("No readiness data for", date)
 		return
 	}
 
-	v := data.Data[0]
+	c := r.Contributors
 
-	fmt.Printf("🏋️  VO2 Max - %s\n", v.Day)
-	fmt.Println(strings.Repeat("─", 40))
-	fmt.Printf("VO2 Max:  %.1f ml/kg/min\n", v.VO2Max)
+	fmt.Printf("%sReadiness - %s\n", sym.Readiness, headerDay(date, r.Day))
+	fmt.Println(rule(40))
+	rows := []kv{
+		{"Score", optInt(r.Score, "")},
+		{"Temp Deviation", optTemp(r.TemperatureDeviation)},
+	}
+	if r.TemperatureTrendDeviation != nil {
+		rows = append(rows, kv{"Temp Trend", optTemp(r.TemperatureTrendDeviation)})
+	}
+	var v rowView
+	v.add(20, rows...)
+	v.add(20, kv{})
+	v.line("Contributors:", kv{Key: "Contributors"})
+	v.contributors([]contributor{
+		{"Resting HR", "resting_heart_rate", c.RestingHeartRate},
+		{"HRV Balance", "hrv_balance", c.HRVBalance},
+		{"Body Temp", "body_temperature", c.BodyTemperature},
+		{"Recovery Index", "recovery_index", c.RecoveryIndex},
+		{"Previous Night", "previous_night", c.PreviousNight},
+		{"Prev Day Activity", "previous_day_activity", c.PreviousDayActivity},
+		{"Activity Balance", "activity_balance", c.ActivityBalance},
+		{"Sleep Balance", "sleep_balance", c.SleepBalance},
+		{"Sleep Regularity", "sleep_regularity", c.SleepRegularity},
+	}, 18)
+	v.print()
 }
 
-func fetchWorkouts(date string) {
-	params := url.Values{}
-	params.Set("start_date", date)
-	params.Set("end_date", date)
+// contributor is a named 0-100 contributor score, nil when the API
+// omitted it.
+type contributor struct {
+	Label string
+	Key   string
+	Value *int
+}
 
-	body, err := apiGet("/workout", params)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+// contributors adds contributors one per line in a column of width, or
+// as bars with --chart. Missing ones are left out.
+func (v *rowView) contributors(cs []contributor, width int) {
+	var labels []string
+	var values []int
+	var rows []kv
+	for _, c := range cs {
+		if c.Value == nil {
+			continue
+		}
+		labels = append(labels, c.Label)
+		values = append(values, *c.Value)
+		rows = append(rows, kv{"  " + c.Label, strconv.Itoa(*c.Value) + explain(c.Key, *c.Value)})
 	}
-
-	var data WorkoutResponse
-	json.Unmarshal(body, &data)
-
-	if len(data.Data) == 0 {
-		fmt.Println("No workout data for", date)
+	if *chartFlag {
+		v.bars(labels, values)
 		return
 	}
+	v.add(width+2, rows...)
+}
 
-	fmt.Printf("🏋️  Workouts - %s\n", date)
-	fmt.Println(strings.Repeat("─", 40))
+// contributorInfo explains each readiness and sleep contributor, keyed by
+// its API field name, for --explain.
+var contributorInfo = map[string]string{
+	"resting_heart_rate":    "how your overnight lowest heart rate compares to your norm",
+	"hrv_balance":           "recent heart rate variability against your long-term average",
+	"body_temperature":      "how far body temperature strayed from your baseline",
+	"recovery_index":        "how early in the night your heart rate settled",
+	"previous_night":        "last night's sleep score",
+	"previous_day_activity": "yesterday's activity and inactivity",
+	"activity_balance":      "activity over the past two weeks against your norm",
+	"sleep_balance":         "sleep over the past two weeks against your need",
+	"sleep_regularity":      "how consistent your sleep and wake times are",
+	"total_sleep":           "total time asleep against your need",
+	"efficiency":            "share of time in bed actually asleep",
+	"restfulness":           "wake-ups, movement and getting up during the night",
+	"rem_sleep":             "time in REM sleep, which supports memory and learning",
+	"deep_sleep":            "time in deep sleep, which supports physical recovery",
+	"latency":               "how long it took to fall asleep",
+	"timing":                "whether sleep fell in line with your body clock",
+}
+
+// explain returns the --explain description for a contributor, padded
+// to line up after its score, or "" when --explain is off.
+func explain(field string, score int) string {
+	if !*explainFlag {
+		return ""
+	}
+	return fmt.Sprintf("%*s%s", 5-len(fmt.Sprint(score)), "", contributorInfo[field])
+}
+
+func fetchActivity(date string) {
+	a, err := getActivity(date)
+	if err != nil {
+		fatal(err)
+	}
+	printActivity(date, a)
+}
+
+func printActivity(date string, a *ActivityRecord) {
+	if a == nil {
+		fmt.Println("No activity data for", date)
+		return
+	}
+
+	fmt.Printf("%sActivity - %s\n", sym.Activity, headerDay(date, a.Day))
+	fmt.Println(rule(40))
+	rows := []kv{
+		{"Score", optInt(a.Score, "")},
+		{"Steps", strconv.Itoa(a.Steps)},
+		{"Distance", formatDistance(float64(a.EquivalentWalkingDist), 1)},
+		{},
+		{"Active Cal", strconv.Itoa(a.ActiveCalories)},
+		{"Total Cal", strconv.Itoa(a.TotalCalories)},
+		{"Target Cal", strconv.Itoa(a.TargetCalories)},
+		{},
+		{"High Activity", formatDuration(a.HighActivityTime)},
+		{"Med Activity", formatDuration(a.MediumActivityTime)},
+		{"Low Activity", formatDuration(a.LowActivityTime)},
+		{"Sedentary", formatDuration(a.SedentaryTime)},
+		{"Resting", formatDuration(a.RestingTime)},
+	}
+	if *metMinFlag {
+		rows = append(rows, kv{"MET Minutes", fmt.Sprintf("%.0f", metMinutes(a))})
+	}
+	printRows(15, rows)
+
+	if *hourlyFlag {
+		fmt.Println()
+		printHourlyActivity(a)
+	}
+
+	if *goalFlag {
+		fmt.Println()
+		printGoal("Calorie Goal:", a.ActiveCalories, a.TargetCalories, "cal")
+		printGoal("Step Goal:", a.Steps, stepGoal(), "steps")
+	}
+}
+
+// printHourlyActivity draws one row per hour, one character per 5-minute
+// class, followed by the minutes of low or higher activity in that hour.
+func printHourlyActivity(a *ActivityRecord) {
+	if a.Class5Min == "" {
+		fmt.Println("No 5-minute activity data for", a.Day)
+		return
+	}
+
+	// The activity day starts at 4 AM local; the MET series records the
+	// exact start when present.
+	day, _ := time.ParseInLocation("2006-01-02", a.Day, time.Local)
+	start := day.Add(4 * time.Hour)
+	if a.MET != nil {
+		if ts, err := time.Parse(time.RFC3339, a.MET.Timestamp); err == nil {
+			start = ts.Local()
+		}
+	}
+
+	fmt.Println("Hourly Activity:")
+	for h := 0; h*12 < len(a.Class5Min); h++ {
+		bins := a.Class5Min[h*12 : min((h+1)*12, len(a.Class5Min))]
+		var chart strings.Builder
+		active := 0
+		for _, c := range bins {
+			class := int(c - '0')
+			if class < 0 || class >= len(sym.ActivityClasses) {
+				class = 0
+			}
+			chart.WriteString(sym.ActivityClasses[class])
+			if class >= 3 {
+				active += 5
+			}
+		}
+		hour := start.Add(time.Duration(h) * time.Hour)
+		fmt.Printf("  %5s  %-12s %3dm\n", hour.Format(hourLayout()), chart.String(), active)
+	}
+	c := sym.ActivityClasses
+	fmt.Printf("  %s rest  %s inactive  %s low  %s medium  %s high\n", c[1], c[2], c[3], c[4], c[5])
+}
+
+// printGoal prints the percentage of target reached. The bar is clamped
+// at 100% but the real percentage is always shown.
+func printGoal(label string, value, target int, unit string) {
+	if target <= 0 {
+		return
+	}
+	pct := value * 100 / target
+	fmt.Printf("%-14s %d%% (%d/%d %s)\n", label, pct, value, target, unit)
+	if *chartFlag {
+		fmt.Printf("               %s\n", bar(pct, 20))
+	}
+}
+
+// heartRateStats describes the readings' BPM. With no readings it is the
+// zero distribution.
+func heartRateStats(readings []HeartRateRecord) distribution {
+	if len(readings) == 0 {
+		return distribution{}
+	}
+	bpm := make([]float64, len(readings))
+	for i, hr := range readings {
+		bpm[i] = float64(hr.BPM)
+	}
+	return describe(bpm)
+}
+
+func fetchHeartRate(date string) {
+	readings, err := getHeartRate(date)
+	if err != nil {
+		fatal(err)
+	}
+	printHeartRate(date, readings)
+}
+
+func printHeartRate(date string, readings []HeartRateRecord) {
+	if len(readings) == 0 {
+		fmt.Println("No heart rate data for", date)
+		return
+	}
+
+	d := heartRateStats(readings)
+	avg := formatFloat(d.Mean, 0)
+
+	fmt.Printf("%sHeart Rate - %s\n", sym.HeartRate, date)
+	fmt.Println(rule(40))
+	rows := []kv{
+		{"Readings", strconv.Itoa(len(readings))},
+		{"Min", fmt.Sprintf("%.0f bpm", d.Min)},
+		{"Max", fmt.Sprintf("%.0f bpm", d.Max)},
+	}
+	switch *averageFlag {
+	case "simple":
+		rows = append(rows, kv{"Average", avg + " bpm"})
+	case "weighted":
+		rows = append(rows,
+			kv{"Average", avg + " bpm (simple)"},
+			kv{"", formatFloat(weightedAverage(readings), 0) + " bpm (time-weighted)"})
+	default:
+		fmt.Fprintf(os.Stderr, "unknown --average %q (want simple or weighted)\n", *averageFlag)
+		os.Exit(1)
+	}
+	if *stdDevFlag {
+		rows = append(rows, kv{"Std Dev", formatFloat(d.StdDev, 1) + " bpm"})
+	}
+	if *restingFlag {
+		bpm, method := restingHeartRate(readings)
+		rows = append(rows, kv{"Resting", formatFloat(bpm, 0) + " bpm (" + method + ")"})
+	}
+	printRows(11, rows)
+
+	if *intervalFlag > 0 {
+		fmt.Println()
+		printIntervals(date, readings, *intervalFlag)
+	}
+
+	if *rawFlag {
+		fmt.Println()
+		printReadings(limitReadings(downsample(readings, *everyFlag), *limitFlag))
+	}
+}
+
+// restingWindow and restingMinReadings define a sustained stretch for
+// restingHeartRate.
+const (
+	restingWindow      = 10 * time.Minute
+	restingMinReadings = 3
+)
+
+// restingHeartRate estimates resting heart rate as the lowest average
+// over any restingWindow holding at least restingMinReadings readings,
+// which a single low outlier can't drag down the way it does Min. Days
+// without such a stretch fall back to the 5th percentile of readings.
+// The second result names the method used.
+func restingHeartRate(readings []HeartRateRecord) (float64, string) {
+	times := make([]time.Time, len(readings))
+	for i, r := range readings {
+		times[i], _ = time.Parse(time.RFC3339, r.Timestamp)
+	}
+
+	lowest := math.Inf(1)
+	for i := range readings {
+		var sum float64
+		n := 0
+		for j := i; j < len(readings) && times[j].Sub(times[i]) <= restingWindow; j++ {
+			sum += float64(readings[j].BPM)
+			n++
+		}
+		if n >= restingMinReadings {
+			lowest = min(lowest, sum/float64(n))
+		}
+	}
+	if !math.IsInf(lowest, 1) {
+		return lowest, fmt.Sprintf("lowest %d-minute average", int(restingWindow.Minutes()))
+	}
+
+	bpm := make([]float64, len(readings))
+	for i, r := range readings {
+		bpm[i] = float64(r.BPM)
+	}
+	slices.Sort(bpm)
+	return bpm[int(math.Ceil(0.05*float64(len(bpm))))-1], "5th percentile of readings"
+}
+
+// maxReadingGap caps how long one reading can count for in the weighted
+// average, so a gap while the ring was off doesn't dominate it.
+const maxReadingGap = 10 * time.Minute
+
+// weightedAverage weights each reading by the time until the next one.
+// Readings arrive in irregular bursts, so this is fairer than the mean.
+func weightedAverage(readings []HeartRateRecord) float64 {
+	var sum, total float64
+	for i := 0; i < len(readings)-1; i++ {
+		t0, err0 := time.Parse(time.RFC3339, readings[i].Timestamp)
+		t1, err1 := time.Parse(time.RFC3339, readings[i+1].Timestamp)
+		if err0 != nil || err1 != nil || !t1.After(t0) {
+			continue
+		}
+		gap := min(t1.Sub(t0), maxReadingGap).Seconds()
+		sum += float64(readings[i].BPM) * gap
+		total += gap
+	}
+	if total == 0 {
+		return heartRateStats(readings).Mean
+	}
+	return sum / total
+}
+
+// downsample keeps the first reading in each interval. A zero interval
+// keeps every reading.
+func downsample(readings []HeartRateRecord, every time.Duration) []HeartRateRecord {
+	if every <= 0 {
+		return readings
+	}
+	var kept []HeartRateRecord
+	var next time.Time
+	for _, hr := range readings {
+		ts, err := time.Parse(time.RFC3339, hr.Timestamp)
+		if err != nil {
+			continue
+		}
+		if ts.Before(next) {
+			continue
+		}
+		kept = append(kept, hr)
+		next = ts.Truncate(every).Add(every)
+	}
+	return kept
+}
+
+// limitReadings returns the first n readings, or the last -n when n is
+// negative. Zero returns all readings.
+func limitReadings(readings []HeartRateRecord, n int) []HeartRateRecord {
+	switch {
+	case n > 0 && n < len(readings):
+		return readings[:n]
+	case n < 0 && -n < len(readings):
+		return readings[len(readings)+n:]
+	}
+	return readings
+}
+
+// validInterval reports whether d is a usable --interval for heartrate:
+// whole minutes, at most a day, splitting the day into equal buckets.
+func validInterval(d time.Duration) bool {
+	return d >= time.Minute && d <= 24*time.Hour && d%time.Minute == 0 && (24*time.Hour)%d == 0
+}
+
+// printIntervals prints the min, mean and max BPM for each interval of
+// date's local day. Intervals without readings show a dash.
+func printIntervals(date string, readings []HeartRateRecord, interval time.Duration) {
+	midnight, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+	n := int((24*time.Hour + interval - 1) / interval)
+	buckets := make([][]float64, n)
+	for _, hr := range readings {
+		ts, err := time.Parse(time.RFC3339, hr.Timestamp)
+		if err != nil {
+			continue
+		}
+		offset := ts.Sub(midnight)
+		if offset < 0 || offset >= 24*time.Hour {
+			continue
+		}
+		i := int(offset / interval)
+		buckets[i] = append(buckets[i], float64(hr.BPM))
+	}
+
+	fmt.Printf("%-10s %4s %4s %4s\n", "Time", "Min", "Avg", "Max")
+	// %4s pads by bytes; the dash may be multi-byte, so pad it by runes.
+	dash := strings.Repeat(" ", max(0, 4-utf8.RuneCountInString(sym.Dash))) + sym.Dash
+	for i, b := range buckets {
+		label := midnight.Add(time.Duration(i) * interval).Format(clockLayout())
+		if len(b) == 0 {
+			fmt.Printf("%-10s %s %s %s\n", label, dash, dash, dash)
+			continue
+		}
+		d := describe(b)
+		fmt.Printf("%-10s %4.0f %4s %4.0f\n", label, d.Min, formatFloat(d.Mean, 0), d.Max)
+	}
+}
+
+func printReadings(readings []HeartRateRecord) {
+	fmt.Println("Time        BPM  Source")
+	for _, hr := range readings {
+		ts, _ := time.Parse(time.RFC3339, hr.Timestamp)
+		fmt.Printf("%-10s %4d  %s\n", ts.Local().Format(clockLayout()), hr.BPM, hr.Source)
+	}
+}
+
+func fetchStress(date string) {
+	s, err := getStress(date)
+	if err != nil {
+		fatal(err)
+	}
+	printStress(date, s)
+}
+
+func printStress(date string, s *StressRecord) {
+	if s == nil {
+		fmt.Println("No stress data for", date)
+		return
+	}
+
+	fmt.Printf("%sStress - %s\n", sym.Stress, headerDay(date, s.Day))
+	fmt.Println(rule(40))
+	printRows(17, []kv{
+		{"Stress High", optDuration(s.StressHigh)},
+		{"Recovery High", optDuration(s.RecoveryHigh)},
+		{"Day Summary", stressSummary(s.DaySummary)},
+	})
+}
+
+// stressSummary formats a day's stress summary, or n/a if the API hasn't
+// settled on one yet.
+func stressSummary(v *string) string {
+	if v == nil || *v == "" {
+		return na
+	}
+	return *v
+}
+
+func fetchSpO2(date string) {
+	params := url.Values{}
+	params.Set("start_date", date)
+	params.Set("end_date", date)
+
+	body, err := client.Get(ctx, "/daily_spo2", params)
+	if err != nil {
+		fatal(err)
+	}
+
+	var data SpO2Response
+	if err := decodeResponse(body, &data); err != nil {
+		fatal(err)
+	}
+
+	if len(data.Data) == 0 {
+		fmt.Println("No SpO2 data for", date)
+		return
+	}
+
+	s := data.Data[0]
+
+	fmt.Printf("%sBlood Oxygen - %s\n", sym.SpO2, headerDay(date, s.Day))
+	fmt.Println(rule(40))
+	stats := dailySpO2Stats(s)
+	rows := []kv{{"Average SpO2", optFloat(stats.Average, 1, "%")}}
+	switch {
+	case stats.Lowest != nil:
+		rows = append(rows, kv{"Lowest SpO2", optFloat(stats.Lowest, 1, "%")})
+	case stats.Average != nil:
+		rows = append(rows, kv{"", "(nightly average only; the daily endpoint has no per-sample lows)"})
+	}
+	rows = append(rows, kv{"Breathing Index", optFloat(s.BreathingDisturbanceIndex, 2, "")})
+	printRows(17, rows)
+}
+
+// spo2Stats is one night's SpO2. Lowest needs per-sample data, which the
+// daily endpoint doesn't provide, so it stays nil until a sample source
+// such as /spo2 fills it in.
+type spo2Stats struct {
+	Average *float64
+	Lowest  *float64
+}
+
+func dailySpO2Stats(r SpO2Record) spo2Stats {
+	if r.SpO2Percentage == nil {
+		return spo2Stats{}
+	}
+	return spo2Stats{Average: &r.SpO2Percentage.Average}
+}
+
+func fetchResilience(date string) {
+	params := url.Values{}
+	params.Set("start_date", date)
+	params.Set("end_date", date)
+
+	body, err := client.Get(ctx, "/daily_resilience", params)
+	if err != nil {
+		fatal(err)
+	}
+
+	var data ResilienceResponse
+	if err := decodeResponse(body, &data); err != nil {
+		fatal(err)
+	}
+
+	if len(data.Data) == 0 {
+		fmt.Println("No resilience data for", date)
+		return
+	}
+
+	r := data.Data[0]
+
+	c := r.Contributors
+
+	fmt.Printf("%sResilience - %s\n", sym.Resilience, headerDay(date, r.Day))
+	fmt.Println(rule(40))
+	var v rowView
+	if i := slices.IndexFunc(resilienceLevels, func(l resilienceLevel) bool { return l.Name == r.Level }); i >= 0 {
+		l := resilienceLevels[i]
+		label := fmt.Sprintf("%s%s (%d/%d)", strings.ToUpper(l.Name[:1]), l.Name[1:], i+1, len(resilienceLevels))
+		v.add(18, kv{"Level", colorize(l.Color, label)}, kv{"", l.Note})
+	} else {
+		v.add(18, kv{"Level", r.Level})
+	}
+	v.add(18, kv{})
+	v.line("Contributors:", kv{Key: "Contributors"})
+	if *chartFlag {
+		v.bars(
+			[]string{"Sleep Recovery", "Daytime Recovery", "Stress"},
+			[]int{int(math.Round(c.SleepRecovery)), int(math.Round(c.DaytimeRecovery)), int(math.Round(c.Stress))},
+		)
+	} else {
+		v.add(20,
+			kv{"  Sleep Recovery", fmt.Sprintf("%.0f", c.SleepRecovery)},
+			kv{"  Daytime Recovery", fmt.Sprintf("%.0f", c.DaytimeRecovery)},
+			kv{"  Stress", fmt.Sprintf("%.0f", c.Stress)},
+		)
+	}
+	v.print()
+}
+
+type resilienceLevel struct {
+	Name  string
+	Note  string
+	Color string
+}
+
+// resilienceLevels describes each level the API reports, weakest first.
+var resilienceLevels = []resilienceLevel{
+	{"limited", "Recovery is struggling to keep up with stress", colorRed},
+	{"adequate", "Recovery roughly balances stress", colorYellow},
+	{"solid", "Recovery comfortably covers stress", colorGreen},
+	{"strong", "Well recovered with good stress capacity", colorGreen},
+	{"exceptional", "Excellent recovery and stress capacity", colorCyan},
+}
+
+func fetchVO2Max(date string) {
+	params := url.Values{}
+	params.Set("start_date", date)
+	params.Set("end_date", date)
+
+	body, err := getEndpoint("/vO2_max", params)
+	if err != nil {
+		fatal(err)
+	}
+
+	var data VO2MaxResponse
+	if err := decodeResponse(body, &data); err != nil {
+		fatal(err)
+	}
+
+	if len(data.Data) == 0 {
+		fmt.Println("No VO2 max data for", date)
+		return
+	}
+
+	v := data.Data[0]
+
+	fmt.Printf("%sVO2 Max - %s\n", sym.Workout, headerDay(date, v.Day))
+	fmt.Println(rule(40))
+	printRows(10, []kv{{"VO2 Max", optFloat(v.VO2Max, 1, " ml/kg/min")}})
+}
+
+// workoutTotals sums workouts for the per-day and range total lines.
+type workoutTotals struct {
+	Count    int
+	Duration time.Duration
+	Calories float64
+	Distance float64
+}
+
+func (t *workoutTotals) add(w WorkoutRecord) {
+	start, _ := time.Parse(time.RFC3339, w.StartDatetime)
+	end, _ := time.Parse(time.RFC3339, w.EndDatetime)
+	t.Count++
+	t.Duration += end.Sub(start)
+	t.Calories += w.Calories
+	t.Distance += w.Distance
+}
+
+func (t *workoutTotals) merge(o workoutTotals) {
+	t.Count += o.Count
+	t.Duration += o.Duration
+	t.Calories += o.Calories
+	t.Distance += o.Distance
+}
+
+func (t workoutTotals) print(label string) {
+	noun := "workouts"
+	if t.Count == 1 {
+		noun = "workout"
+	}
+	line := fmt.Sprintf("%-12s%d %s, %s, %.0f cal", label, t.Count, noun, formatDuration(int(t.Duration.Seconds())), t.Calories)
+	if t.Distance > 0 {
+		line += ", " + formatDistance(t.Distance, 2)
+	}
+	fmt.Println(line)
+}
+
+// fetchWorkouts prints the day's workouts and returns their totals.
+func fetchWorkouts(date string) workoutTotals {
+	params := url.Values{}
+	params.Set("start_date", date)
+	params.Set("end_date", date)
+
+	body, err := client.Get(ctx, "/workout", params)
+	if err != nil {
+		fatal(err)
+	}
+
+	var data WorkoutResponse
+	if err := decodeResponse(body, &data); err != nil {
+		fatal(err)
+	}
+
+	var totals workoutTotals
+	if len(data.Data) == 0 {
+		fmt.Println("No workout data for", date)
+		return totals
+	}
+
+	workouts := filterWorkouts(data.Data)
+	if len(workouts) == 0 {
+		fmt.Printf("No matching workouts for %s (%d filtered out)\n", date, len(data.Data))
+		return totals
+	}
+	sortByTime(workouts, func(w WorkoutRecord) string { return w.StartDatetime })
+
+	fmt.Printf("%sWorkouts - %s\n", sym.Workout, headerDay(date, workouts[0].Day))
+	fmt.Println(rule(40))
+
+	var v rowView
+	for i, w := range workouts {
+		if i > 0 {
+			v.add(12, kv{})
+		}
 
-	for i, w := range data.Data {
-		if i > 0 {
-			fmt.Println()
-		}
-		
 		startTime, _ := time.Parse(time.RFC3339, w.StartDatetime)
 		endTime, _ := time.Parse(time.RFC3339, w.EndDatetime)
 		startTime = startTime.Local()
 		endTime = endTime.Local()
 		duration := endTime.Sub(startTime)
-		
+
 		label := w.Activity
 		if w.Label != nil && *w.Label != "" {
 			label = *w.Label
 		}
-		
-		fmt.Printf("Activity:   %s\n", label)
-		fmt.Printf("Time:       %s (%s)\n", startTime.Format("3:04 PM"), formatDuration(int(duration.Seconds())))
-		fmt.Printf("Calories:   %.0f\n", w.Calories)
+
+		rows := []kv{
+			{"Activity", label},
+			{"Time", startTime.Format(clockLayout()) + " (" + formatDuration(int(duration.Seconds())) + ")"},
+			{"Calories", fmt.Sprintf("%.0f", w.Calories)},
+		}
 		if w.Distance > 0 {
-			fmt.Printf("Distance:   %.2f km\n", w.Distance/1000)
+			rows = append(rows, kv{"Distance", formatDistance(w.Distance, 2)})
 		}
-		fmt.Printf("Intensity:  %s\n", w.Intensity)
-		fmt.Printf("Source:     %s\n", w.Source)
+		rows = append(rows, kv{"Intensity", w.Intensity}, kv{"Source", w.Source})
+		v.add(12, rows...)
+		totals.add(w)
 	}
+	v.print()
+
+	fmt.Println(rule(40))
+	totals.print("Total:")
+	return totals
+}
+
+// filterWorkouts drops workouts not matching --source, --activity and
+// --min-duration. Source and activity match case-insensitively.
+func filterWorkouts(workouts []WorkoutRecord) []WorkoutRecord {
+	var kept []WorkoutRecord
+	for _, w := range workouts {
+		if *sourceFlag != "" && !strings.EqualFold(w.Source, *sourceFlag) {
+			continue
+		}
+		if *activityFlag != "" && !strings.EqualFold(w.Activity, *activityFlag) {
+			continue
+		}
+		if *minDurationFlag > 0 {
+			start, _ := time.Parse(time.RFC3339, w.StartDatetime)
+			end, _ := time.Parse(time.RFC3339, w.EndDatetime)
+			if end.Sub(start) < *minDurationFlag {
+				continue
+			}
+		}
+		kept = append(kept, w)
+	}
+	return kept
+}
+
+// staleSyncAfter is how old the newest heart rate reading can be before
+// today warns that the ring probably hasn't synced.
+const staleSyncAfter = 12 * time.Hour
+
+func fetchToday() {
+	date := time.Now().Format("2006-01-02")
+	warnStaleSync(date)
+	fetchAll(date)
+}
+
+// warnStaleSync prints a warning when the newest heart rate reading is
+// older than staleSyncAfter. Errors are ignored; fetchAll reports them.
+func warnStaleSync(date string) {
+	// Shortly after midnight today may be empty, so look at yesterday too.
+	latest, err := latestReading(date, 2)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case latest.IsZero():
+		fmt.Println(sym.Warning + "No recent heart rate readings - open the Oura app to sync your ring")
+		fmt.Println()
+	case time.Since(latest) > staleSyncAfter:
+		fmt.Printf("%sLast ring data is %s old - open the Oura app to sync your ring\n", sym.Warning, time.Since(latest).Round(time.Hour))
+		fmt.Println()
+	}
+}
+
+// latestReading returns the newest heart rate timestamp, looking back
+// from date one day at a time for up to days days. It is zero if there
+// were no readings.
+func latestReading(date string, days int) (time.Time, error) {
+	d, _ := time.Parse("2006-01-02", date)
+	for i := 0; i < days; i++ {
+		readings, err := getHeartRate(d.AddDate(0, 0, -i).Format("2006-01-02"))
+		if err != nil {
+			return time.Time{}, err
+		}
+		var latest time.Time
+		for _, hr := range readings {
+			if ts, err := time.Parse(time.RFC3339, hr.Timestamp); err == nil && ts.After(latest) {
+				latest = ts
+			}
+		}
+		if !latest.IsZero() {
+			return latest, nil
+		}
+	}
+	return time.Time{}, nil
 }
 
 func fetchAll(date string) {
-	fmt.Printf("╔══════════════════════════════════════╗\n")
-	fmt.Printf("║      OURA METRICS - %-10s       ║\n", date)
-	fmt.Printf("╚══════════════════════════════════════╝\n\n")
+	selected, err := selectedSections()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *compactFlag {
+		fetchAllCompact(date, selected)
+	} else {
+		fetchAllFull(date, selected)
+	}
+	if *baselineFlag != "" {
+		fmt.Println()
+		printBaseline(date, *baselineFlag)
+	}
+}
 
-	fetchReadiness(date)
-	fmt.Println()
-	fetchSleep(date)
+func fetchAllFull(date string, selected []allSection) {
+	fmt.Println(sym.BoxTop)
+	fmt.Printf("%s      OURA METRICS - %-10s       %s\n", sym.BoxSide, date, sym.BoxSide)
+	fmt.Println(sym.BoxBottom)
 	fmt.Println()
-	fetchActivity(date)
-	fmt.Println()
-	fetchStress(date)
-	fmt.Println()
-	fetchHeartRate(date)
+
+	for i, sec := range selected {
+		if i > 0 {
+			fmt.Println()
+		}
+		sec.Full(date)
+	}
+}
+
+// allSection is one metric shown by all, fetched only when selected.
+type allSection struct {
+	Name    string
+	Full    func(date string)
+	Compact func(date string) section
+}
+
+// allSections are the sections of all, in display order. A token
+// without an endpoint's scope gets a 403 there; that section is skipped
+// rather than losing the rest.
+var allSections = []allSection{
+	{
+		Name: "readiness",
+		Full: func(date string) {
+			r, day, err := withFallback(date, getReadiness, isNil)
+			if sectionOK("Readiness", err) {
+				printReadiness(day, r)
+			}
+		},
+		Compact: func(date string) section {
+			r, day, err := withFallback(date, getReadiness, isNil)
+			return compactSection(err, day != date, func() section { return readinessSection(r) })
+		},
+	},
+	{
+		Name: "sleep",
+		Full: func(date string) {
+			sleep, day, err := withFallback(date, getSleepDay, sleepDay.empty)
+			if sectionOK("Sleep", err) {
+				printSleep(day, sleep.Daily, sleep.Periods)
+			}
+		},
+		Compact: func(date string) section {
+			sleep, day, err := withFallback(date, getSleepDay, sleepDay.empty)
+			return compactSection(err, day != date, func() section { return sleepSection(sleep.Daily, sleep.Periods) })
+		},
+	},
+	{
+		Name: "activity",
+		Full: func(date string) {
+			a, day, err := withFallback(date, getActivity, isNil)
+			if sectionOK("Activity", err) {
+				printActivity(day, a)
+			}
+		},
+		Compact: func(date string) section {
+			a, day, err := withFallback(date, getActivity, isNil)
+			return compactSection(err, day != date, func() section { return activitySection(a) })
+		},
+	},
+	{
+		Name: "stress",
+		Full: func(date string) {
+			st, day, err := withFallback(date, getStress, isNil)
+			if sectionOK("Stress", err) {
+				printStress(day, st)
+			}
+		},
+		Compact: func(date string) section {
+			st, day, err := withFallback(date, getStress, isNil)
+			return compactSection(err, day != date, func() section { return stressSection(st) })
+		},
+	},
+	{
+		Name: "heartrate",
+		Full: func(date string) {
+			hr, day, err := withFallback(date, getHeartRate, noReadings)
+			if sectionOK("Heart Rate", err) {
+				printHeartRate(day, hr)
+			}
+		},
+		Compact: func(date string) section {
+			hr, day, err := withFallback(date, getHeartRate, noReadings)
+			return compactSection(err, day != date, func() section { return heartRateSection(hr) })
+		},
+	},
+}
+
+// selectedSections applies --only and --skip to allSections.
+func selectedSections() ([]allSection, error) {
+	var names []string
+	for _, sec := range allSections {
+		names = append(names, sec.Name)
+	}
+	parse := func(flag, value string) (map[string]bool, error) {
+		set := map[string]bool{}
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !slices.Contains(names, name) {
+				return nil, fmt.Errorf("unknown section %q in %s (want: %s)", name, flag, strings.Join(names, ", "))
+			}
+			set[name] = true
+		}
+		return set, nil
+	}
+	only, err := parse("--only", *onlyFlag)
+	if err != nil {
+		return nil, err
+	}
+	skip, err := parse("--skip", *skipFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []allSection
+	for _, sec := range allSections {
+		if (len(only) == 0 || only[sec.Name]) && !skip[sec.Name] {
+			selected = append(selected, sec)
+		}
+	}
+	return selected, nil
+}
+
+func noReadings(hr []HeartRateRecord) bool { return len(hr) == 0 }
+
+// withFallback gets date's data. With --yesterday-fallback, a day with
+// no data is retried one day back; the returned label then reads
+// "YYYY-MM-DD (yesterday)".
+func withFallback[T any](date string, get func(string) (T, error), empty func(T) bool) (T, string, error) {
+	v, err := get(date)
+	if err != nil || !*yesterdayFallbackFlag || !empty(v) {
+		return v, date, err
+	}
+	d, _ := time.Parse("2006-01-02", date)
+	yesterday := d.AddDate(0, 0, -1).Format("2006-01-02")
+	if prev, err := get(yesterday); err == nil && !empty(prev) {
+		return prev, yesterday + " (yesterday)", nil
+	}
+	return v, date, nil
+}
+
+func isNil[T any](v *T) bool { return v == nil }
+
+// sleepDay is a day's sleep score and periods, fetched together.
+type sleepDay struct {
+	Daily   *DailySleepRecord
+	Periods []SleepRecord
+}
+
+func (s sleepDay) empty() bool { return s.Daily == nil && len(s.Periods) == 0 }
+
+func getSleepDay(date string) (sleepDay, error) {
+	daily, _ := getDailySleep(date)
+	periods, err := getSleepPeriods(date)
+	return sleepDay{daily, periods}, err
+}
+
+// sectionOK reports whether a section of all can be printed. A scope
+// error prints a note instead; any other error is fatal.
+func sectionOK(name string, err error) bool {
+	if isScopeError(err) {
+		fmt.Printf("%s: skipped (insufficient scope)\n", name)
+		return false
+	}
+	if err != nil {
+		fatal(err)
+	}
+	return true
+}
+
+// kv is a single labeled value within a section.
+type kv struct {
+	Key   string
+	Value string
+}
+
+// section is a titled group of values for the compact layout.
+type section struct {
+	Title string
+	Rows  []kv
+}
+
+func fetchAllCompact(date string, selected []allSection) {
+	var sections []section
+	for _, sec := range selected {
+		sections = append(sections, sec.Compact(date))
+	}
+
+	colWidth, perRow := compactGrid()
+	fmt.Printf("OURA METRICS - %s\n", date)
+	fmt.Println(rule(colWidth * perRow))
+	renderCompact(sections)
+}
+
+// compactSection builds a section, or a "skipped" placeholder when the
+// token lacks the endpoint's scope. Other errors are fatal. A section
+// showing --yesterday-fallback data is titled as such.
+func compactSection(err error, yesterday bool, build func() section) section {
+	if isScopeError(err) {
+		sec := build()
+		sec.Rows = []kv{{"", "skipped (insufficient scope)"}}
+		return sec
+	}
+	if err != nil {
+		fatal(err)
+	}
+	sec := build()
+	if yesterday {
+		sec.Title += " (yesterday)"
+	}
+	return sec
+}
+
+var noData = []kv{{"", "no data"}}
+
+func readinessSection(r *ReadinessRecord) section {
+	sec := section{Title: "READINESS", Rows: noData}
+	if r == nil {
+		return sec
+	}
+	sec.Rows = []kv{
+		{"Score", optInt(r.Score, "")},
+		{"Temp Dev", optTemp(r.TemperatureDeviation)},
+		{"Resting HR", optInt(r.Contributors.RestingHeartRate, "")},
+	}
+	if r.Contributors.HRVBalance != nil {
+		sec.Rows = append(sec.Rows, kv{"HRV Balance", fmt.Sprint(*r.Contributors.HRVBalance)})
+	}
+	return sec
+}
+
+func sleepSection(daily *DailySleepRecord, periods []SleepRecord) section {
+	sec := section{Title: "SLEEP", Rows: noData}
+	if daily == nil && len(periods) == 0 {
+		return sec
+	}
+	sec.Rows = nil
+	if daily != nil {
+		sec.Rows = append(sec.Rows, kv{"Score", optInt(daily.Score, "")})
+	}
+	if len(periods) > 0 {
+		var total, deep, rem int
+		for _, p := range periods {
+			total += p.TotalSleepDuration
+			deep += p.DeepSleepDuration
+			rem += p.RemSleepDuration
+		}
+		sec.Rows = append(sec.Rows,
+			kv{"Total", formatDuration(total)},
+			kv{"Deep", formatDuration(deep)},
+			kv{"REM", formatDuration(rem)},
+			kv{"Periods", fmt.Sprint(len(periods))},
+		)
+	}
+	return sec
+}
+
+func activitySection(a *ActivityRecord) section {
+	sec := section{Title: "ACTIVITY", Rows: noData}
+	if a == nil {
+		return sec
+	}
+	sec.Rows = []kv{
+		{"Score", optInt(a.Score, "")},
+		{"Steps", fmt.Sprint(a.Steps)},
+		{"Active Cal", fmt.Sprint(a.ActiveCalories)},
+		{"Distance", formatDistance(float64(a.EquivalentWalkingDist), 1)},
+	}
+	return sec
+}
+
+func stressSection(s *StressRecord) section {
+	sec := section{Title: "STRESS", Rows: noData}
+	if s == nil {
+		return sec
+	}
+	sec.Rows = []kv{
+		{"Stress High", optDuration(s.StressHigh)},
+		{"Recovery", optDuration(s.RecoveryHigh)},
+		{"Summary", stressSummary(s.DaySummary)},
+	}
+	return sec
+}
+
+func heartRateSection(readings []HeartRateRecord) section {
+	sec := section{Title: "HEART RATE", Rows: noData}
+	if len(readings) == 0 {
+		return sec
+	}
+	d := heartRateStats(readings)
+	sec.Rows = []kv{
+		{"Min", fmt.Sprintf("%.0f bpm", d.Min)},
+		{"Max", fmt.Sprintf("%.0f bpm", d.Max)},
+		{"Average", fmt.Sprintf("%s bpm", formatFloat(d.Mean, 0))},
+		{"Readings", fmt.Sprint(len(readings))},
+	}
+	return sec
+}
+
+// compactGrid returns the column width and columns per row for the
+// compact layout: two fixed columns, or as many as fit with --wide.
+func compactGrid() (colWidth, perRow int) {
+	const minColWidth = 38
+	if !adaptiveWidth() {
+		return minColWidth, 2
+	}
+	width := outputWidth()
+	perRow = max(1, width/minColWidth)
+	return width / perRow, perRow
+}
+
+// renderCompact lays sections out side by side as key/value grids.
+func renderCompact(sections []section) {
+	colWidth, perRow := compactGrid()
+	for i := 0; i < len(sections); i += perRow {
+		row := sections[i:min(i+perRow, len(sections))]
+		height := 0
+		for _, sec := range row {
+			height = max(height, len(sec.Rows))
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+		var title string
+		for _, sec := range row {
+			title += fmt.Sprintf("%-*s", colWidth, sec.Title)
+		}
+		fmt.Println(strings.TrimRight(title, " "))
+		for line := 0; line < height; line++ {
+			var out string
+			for _, sec := range row {
+				cell := ""
+				if line < len(sec.Rows) {
+					cell = fmt.Sprintf("  %-12s %s", sec.Rows[line].Key, sec.Rows[line].Value)
+				}
+				out += fmt.Sprintf("%-*s", colWidth, cell)
+			}
+			fmt.Println(strings.TrimRight(out, " "))
+		}
+	}
 }
 
+// fetchJSON dumps every endpoint's response for date as one JSON object,
+// writing each endpoint as soon as it arrives rather than holding them all.
 func fetchJSON(date string) {
 	params := url.Values{}
 	params.Set("start_date", date)
@@ -950,25 +2861,330 @@ func fetchJSON(date string) {
 		"/vO2_max",
 		"/workout",
 	}
-	
-	result := make(map[string]json.RawMessage)
-	
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	indent, colon := "\n  ", ": "
+	if *jsonCompactFlag {
+		indent, colon = "", ":"
+	} else {
+		enc.SetIndent("  ", "  ")
+	}
+	written := 0
+
+	fmt.Print("{")
 	for _, ep := range endpoints {
-		body, err := apiGet(ep, params)
+		name := strings.TrimPrefix(ep, "/")
+		body, err := getEndpoint(ep, params)
+		if errors.Is(err, context.Canceled) {
+			exitCancelled()
+		}
+		if isScopeError(err) {
+			fmt.Fprintf(os.Stderr, "%s: skipped (insufficient scope)\n", name)
+		}
 		if err != nil {
 			continue
 		}
-		name := strings.TrimPrefix(ep, "/")
-		result[name] = json.RawMessage(body)
+		if !json.Valid(body) {
+			fmt.Fprintf(os.Stderr, "%s: unexpected response from Oura API\n", name)
+			if *verboseFlag {
+				fmt.Fprintf(os.Stderr, "Response body:\n%s\n", body)
+			}
+			continue
+		}
+		if *redactFlag {
+			body = redactJSON(body)
+		}
+		if *anonymizeFlag {
+			body = anonymizeJSON(body, date)
+		}
+		buf.Reset()
+		enc.Encode(json.RawMessage(body))
+		sep := ","
+		if written == 0 {
+			sep = ""
+		}
+		fmt.Printf("%s%s%q%s%s", sep, indent, name, colon, bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+		written++
+	}
+	if written > 0 && !*jsonCompactFlag {
+		fmt.Println()
+	}
+	fmt.Println("}")
+}
+
+// marshalJSON encodes v for output: indented, or on one line with
+// --json-compact.
+func marshalJSON(v any) ([]byte, error) {
+	if *jsonCompactFlag {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// defaultWidth is assumed when the terminal width is unknown.
+const defaultWidth = 80
+
+// adaptiveWidth reports whether layouts should follow outputWidth rather
+// than their fixed default sizes.
+func adaptiveWidth() bool {
+	return *wideFlag || *widthFlag > 0
+}
+
+// outputWidth returns --width if set, else the terminal width, falling
+// back to defaultWidth when stdout isn't a terminal.
+func outputWidth() int {
+	if *widthFlag > 0 {
+		return *widthFlag
 	}
-	
-	out, _ := json.MarshalIndent(result, "", "  ")
-	fmt.Println(string(out))
+	if fd := int(os.Stdout.Fd()); term.IsTerminal(fd) {
+		if w, _, err := term.GetSize(fd); err == nil && w > 0 {
+			return w
+		}
+	}
+	return defaultWidth
+}
+
+// barWidth returns the fixed width, or with --wide the output width less
+// the columns reserved for labels and values.
+func barWidth(fixed, reserved int) int {
+	if !adaptiveWidth() {
+		return fixed
+	}
+	return max(10, outputWidth()-reserved)
+}
+
+// bars adds each 0-100 value as a labeled horizontal bar.
+func (v *rowView) bars(labels []string, values []int) {
+	labelWidth := 0
+	for _, l := range labels {
+		if len(l) > labelWidth {
+			labelWidth = len(l)
+		}
+	}
+	width := barWidth(20, labelWidth+7)
+	for i, l := range labels {
+		b := fmt.Sprintf("%s %3d", bar(values[i], width), values[i])
+		v.line(fmt.Sprintf("  %-*s %s", labelWidth, l, b), kv{"  " + l, b})
+	}
+}
+
+// stageChart shows the proportion of each sleep stage as one bar, in a
+// view whose labels take width.
+func stageChart(s SleepRecord, width int) []kv {
+	stages := []int{s.DeepSleepDuration, s.LightSleepDuration, s.RemSleepDuration, s.AwakeTime}
+	return []kv{
+		{"Stages", stackedBar(stages, sym.Stages, barWidth(40, width))},
+		{"", stageLegend()},
+	}
+}
+
+// stageLegend names the symbol of each sleep stage.
+func stageLegend() string {
+	return fmt.Sprintf("%s deep  %s light  %s REM  %s awake", sym.Stages[0], sym.Stages[1], sym.Stages[2], sym.Stages[3])
+}
+
+// hypnogram draws the night's stages in order, one character per
+// 5 minutes, sampled down when the night is wider than the chart.
+func hypnogram(s SleepRecord, width int) []kv {
+	phases := s.SleepPhase5Min
+	if phases == "" {
+		return []kv{{"Hypnogram", "not available for this record"}}
+	}
+	n := min(len(phases), barWidth(60, width))
+
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		// Phases are 1 deep, 2 light, 3 REM, 4 awake.
+		switch p := phases[i*len(phases)/n]; p {
+		case '1', '2', '3', '4':
+			b.WriteString(sym.Stages[p-'1'])
+		default:
+			b.WriteString(" ")
+		}
+	}
+	return []kv{{"Hypnogram", b.String()}, {"", stageLegend()}}
+}
+
+// hrvChart sparklines the period's overnight HRV series.
+func hrvChart(s SleepRecord, width int) []kv {
+	if s.HRV == nil || !slices.ContainsFunc(s.HRV.Items, func(v *float64) bool { return v != nil }) {
+		return []kv{{"HRV Chart", "not available for this record"}}
+	}
+	line, lo, hi := sparkline(s.HRV.Items, barWidth(60, width))
+	return []kv{{"HRV Chart", line}, {"", fmt.Sprintf("%.0f-%.0f ms", lo, hi)}}
+}
+
+// sparkline draws values in width characters, scaled between their min
+// and max, which it also returns. Nil values are blank. Longer series are
+// sampled evenly, like the hypnogram.
+func sparkline(values []*float64, width int) (line string, lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if v != nil {
+			lo, hi = min(lo, *v), max(hi, *v)
+		}
+	}
+	width = min(width, len(values))
+	levels := len(sym.Spark)
+
+	var b strings.Builder
+	for i := 0; i < width; i++ {
+		v := values[i*len(values)/width]
+		switch {
+		case v == nil:
+			b.WriteString(" ")
+		case hi == lo:
+			b.WriteString(sym.Spark[levels/2])
+		default:
+			b.WriteString(sym.Spark[int((*v-lo)/(hi-lo)*float64(levels-1)+0.5)])
+		}
+	}
+	return b.String(), lo, hi
+}
+
+// stackedBar splits width characters between values in proportion,
+// drawing each share with its symbol.
+func stackedBar(values []int, symbols []string, width int) string {
+	total := 0
+	for _, v := range values {
+		total += max(v, 0)
+	}
+	if total == 0 {
+		return strings.Repeat(" ", width)
+	}
+
+	// Largest-remainder rounding so the shares always add up to width.
+	cells := make([]int, len(values))
+	rem := make([]int, len(values))
+	used := 0
+	for i, v := range values {
+		v = max(v, 0)
+		cells[i] = v * width / total
+		rem[i] = v * width % total
+		used += cells[i]
+	}
+	for ; used < width; used++ {
+		best := 0
+		for i := range rem {
+			if rem[i] > rem[best] {
+				best = i
+			}
+		}
+		cells[best]++
+		rem[best] = -1
+	}
+
+	var b strings.Builder
+	for i, n := range cells {
+		b.WriteString(strings.Repeat(symbols[i], n))
+	}
+	return b.String()
+}
+
+// bar renders a percentage as a fixed-width bar, clamped to 0-100.
+func bar(percent, width int) string {
+	filled := max(0, min(percent, 100)) * width / 100
+	return strings.Repeat(sym.BarFull, filled) + strings.Repeat(sym.BarEmpty, width-filled)
+}
+
+// formatSigned formats a deviation from baseline with an explicit sign.
+// Values that round to zero show as ±0 rather than -0.
+func formatSigned(v float64, decimals int) string {
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if strings.Trim(s, "-0.") == "" {
+		return sym.PlusMinus + strings.TrimPrefix(s, "-")
+	}
+	if v > 0 {
+		return "+" + s
+	}
+	return s
+}
+
+// formatFloat formats v with --precision decimals, or with the caller's
+// default when --precision isn't set.
+func formatFloat(v float64, decimals int) string {
+	if *precisionFlag >= 0 {
+		decimals = *precisionFlag
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// na stands in for a value the API omitted, rather than a misleading 0.
+const na = "n/a"
+
+// optInt formats an optional value followed by suffix, or n/a.
+func optInt(v *int, suffix string) string {
+	if v == nil {
+		return na
+	}
+	return strconv.Itoa(*v) + suffix
+}
+
+// optDuration formats an optional number of seconds like formatDuration,
+// or n/a.
+func optDuration(seconds *int) string {
+	if seconds == nil {
+		return na
+	}
+	return formatDuration(*seconds)
+}
+
+// optFloat formats an optional value like formatFloat, followed by
+// suffix, or n/a.
+func optFloat(v *float64, decimals int, suffix string) string {
+	if v == nil {
+		return na
+	}
+	return formatFloat(*v, decimals) + suffix
+}
+
+// optTemp formats an optional temperature deviation, e.g. +0.12°C, or
+// in °F with imperial units.
+func optTemp(v *float64) string {
+	if v == nil {
+		return na
+	}
+	if config.Units == "imperial" {
+		return formatSigned(*v*1.8, 2) + sym.Degree + "F"
+	}
+	return formatSigned(*v, 2) + sym.Degree + "C"
+}
+
+// formatDistance formats meters as km, or as miles with imperial units.
+func formatDistance(meters float64, decimals int) string {
+	if config.Units == "imperial" {
+		return formatFloat(meters/1609.344, decimals) + " mi"
+	}
+	return formatFloat(meters/1000, decimals) + " km"
+}
+
+// clockLayout is the time.Format layout for clock times: 12-hour by
+// default, 24-hour with --24h.
+func clockLayout() string {
+	if *clock24Flag {
+		return "15:04"
+	}
+	return "3:04 PM"
+}
+
+// hourLayout is clockLayout for whole hours.
+func hourLayout() string {
+	if *clock24Flag {
+		return "15:00"
+	}
+	return "3 PM"
 }
 
 func formatDuration(seconds int) string {
 	h := seconds / 3600
 	m := (seconds % 3600) / 60
+	if *secondsFlag {
+		if h > 0 {
+			return fmt.Sprintf("%dh %dm %ds", h, m, seconds%60)
+		}
+		return fmt.Sprintf("%dm %ds", m, seconds%60)
+	}
 	if h > 0 {
 		return fmt.Sprintf("%dh %dm", h, m)
 	}
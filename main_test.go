@@ -0,0 +1,250 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Timestamps out of order, with offsets that sort wrongly as strings:
+// 23:30-05:00 is 04:30 UTC the next day, after 01:00+00:00.
+var shuffledTimes = []string{
+	"2026-01-01T23:30:00-05:00",
+	"2026-01-01T22:00:00+00:00",
+	"2026-01-02T01:00:00+00:00",
+	"2026-01-02T09:15:00+09:00",
+}
+
+// chronological is shuffledTimes in time order.
+var chronological = []string{
+	"2026-01-01T22:00:00+00:00",
+	"2026-01-02T09:15:00+09:00", // 00:15 UTC
+	"2026-01-02T01:00:00+00:00",
+	"2026-01-01T23:30:00-05:00", // 04:30 UTC
+}
+
+func TestSortByTime(t *testing.T) {
+	t.Run("heart rate", func(t *testing.T) {
+		var readings []HeartRateRecord
+		for _, ts := range shuffledTimes {
+			readings = append(readings, HeartRateRecord{Timestamp: ts})
+		}
+		sortByTime(readings, func(r HeartRateRecord) string { return r.Timestamp })
+		checkOrder(t, readings, func(r HeartRateRecord) string { return r.Timestamp })
+	})
+	t.Run("sleep periods", func(t *testing.T) {
+		var periods []SleepRecord
+		for _, ts := range shuffledTimes {
+			periods = append(periods, SleepRecord{BedtimeStart: ts})
+		}
+		sortByTime(periods, func(s SleepRecord) string { return s.BedtimeStart })
+		checkOrder(t, periods, func(s SleepRecord) string { return s.BedtimeStart })
+	})
+	t.Run("workouts", func(t *testing.T) {
+		var workouts []WorkoutRecord
+		for _, ts := range shuffledTimes {
+			workouts = append(workouts, WorkoutRecord{StartDatetime: ts})
+		}
+		sortByTime(workouts, func(w WorkoutRecord) string { return w.StartDatetime })
+		checkOrder(t, workouts, func(w WorkoutRecord) string { return w.StartDatetime })
+	})
+}
+
+func checkOrder[T any](t *testing.T, records []T, timestamp func(T) string) {
+	t.Helper()
+	var got []string
+	for _, r := range records {
+		got = append(got, timestamp(r))
+	}
+	if !slices.Equal(got, chronological) {
+		t.Errorf("order = %v, want %v", got, chronological)
+	}
+}
+
+// serveAPI points the global client at a test server answering every
+// API request with payload.
+func serveAPI(t *testing.T, payload string) {
+	t.Helper()
+	saved := client
+	t.Cleanup(func() { client = saved })
+	client = newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(payload))
+	}))
+}
+
+// captureStdout returns what f prints to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = saved }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+	f()
+	w.Close()
+	return <-out
+}
+
+// rowValue returns the value printed on the "label:" row of out.
+func rowValue(t *testing.T, out, label string) string {
+	t.Helper()
+	for line := range strings.Lines(out) {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), label+":"); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	t.Fatalf("no %s row in:\n%s", label, out)
+	return ""
+}
+
+func TestMissingValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		fetch   func(date string)
+		want    map[string]string // row label to value
+		absent  []string
+		notes   []string // printed somewhere in the view
+	}{
+		{
+			name:    "readiness without score or contributors",
+			payload: `{"data":[{"day":"2026-01-01","temperature_deviation":null}]}`,
+			fetch:   fetchReadiness,
+			want:    map[string]string{"Score": na, "Temp Deviation": na},
+			absent:  []string{"Resting HR", "HRV Balance"},
+		},
+		{
+			name:    "spo2 without percentage",
+			payload: `{"data":[{"day":"2026-01-01"}]}`,
+			fetch:   fetchSpO2,
+			want:    map[string]string{"Average SpO2": na, "Breathing Index": na},
+			absent:  []string{"per-sample"},
+		},
+		{
+			name:    "spo2 with only the daily average",
+			payload: `{"data":[{"day":"2026-01-01","spo2_percentage":{"average":96.5}}]}`,
+			fetch:   fetchSpO2,
+			want:    map[string]string{"Average SpO2": "96.5%", "Breathing Index": na},
+			absent:  []string{"Lowest SpO2"},
+			notes:   []string{"the daily endpoint has no per-sample lows"},
+		},
+		{
+			name:    "vo2 max without vo2_max",
+			payload: `{"data":[{"day":"2026-01-01"}]}`,
+			fetch:   fetchVO2Max,
+			want:    map[string]string{"VO2 Max": na},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveAPI(t, tt.payload)
+			out := captureStdout(t, func() { tt.fetch("2026-01-01") })
+			for label, want := range tt.want {
+				if got := rowValue(t, out, label); got != want {
+					t.Errorf("%s = %q, want %q", label, got, want)
+				}
+			}
+			for _, label := range tt.absent {
+				if strings.Contains(out, label) {
+					t.Errorf("printed missing %s:\n%s", label, out)
+				}
+			}
+			for _, note := range tt.notes {
+				if !strings.Contains(out, note) {
+					t.Errorf("no %q note in:\n%s", note, out)
+				}
+			}
+		})
+	}
+}
+
+// stressPayload is a /daily_stress response as the API sends it. It also
+// has the day before, which the API can return even for a single day;
+// the view must ignore it.
+const stressPayload = `{"data":[
+	{"id":"a1b2c3","day":"2026-01-01","stress_high":1800,"recovery_high":0,"day_summary":"normal"},
+	{"id":"d4e5f6","day":"2026-01-02","stress_high":5400,"recovery_high":3660,"day_summary":"restored"}
+],"next_token":null}`
+
+func TestStress(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    map[string]string
+	}{
+		{
+			name:    "recorded day, day before ignored",
+			payload: stressPayload,
+			want:    map[string]string{"Stress High": "1h 30m", "Recovery High": "1h 1m", "Day Summary": "restored"},
+		},
+		{
+			name:    "day still in progress",
+			payload: `{"data":[{"id":"d4e5f6","day":"2026-01-02","stress_high":600,"recovery_high":null,"day_summary":null}],"next_token":null}`,
+			want:    map[string]string{"Stress High": "10m", "Recovery High": na, "Day Summary": na},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveAPI(t, tt.payload)
+			out := captureStdout(t, func() { fetchStress("2026-01-02") })
+			if !strings.Contains(out, "Stress - 2026-01-02\n") {
+				t.Errorf("header isn't for 2026-01-02:\n%s", out)
+			}
+			for label, want := range tt.want {
+				if got := rowValue(t, out, label); got != want {
+					t.Errorf("%s = %q, want %q", label, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRangeFlags(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	tests := []struct {
+		from, to         string
+		wantFrom, wantTo string
+		wantErr          bool
+	}{
+		{from: "2021-01-01", to: "today", wantFrom: "2021-01-01", wantTo: today},
+		{from: "yesterday", to: "today", wantFrom: yesterday, wantTo: today},
+		{from: "yesterday", wantFrom: yesterday, wantTo: today},
+		{from: "2021-01-01", to: "2021-01-31", wantFrom: "2021-01-01", wantTo: "2021-01-31"},
+		{from: "today", to: "yesterday", wantErr: true},
+		{from: "2021-01-01", to: "tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+".."+tt.to, func(t *testing.T) {
+			savedFrom, savedTo := *fromFlag, *toFlag
+			t.Cleanup(func() { *fromFlag, *toFlag = savedFrom, savedTo })
+			*fromFlag, *toFlag = tt.from, tt.to
+
+			from, to, err := parseRangeFlags()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %s..%s, want an error", from, to)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("got %s..%s, want %s..%s", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
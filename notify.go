@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// doNotify posts a desktop notification with today's readiness and
+// sleep scores, for use from a login script.
+func doNotify() {
+	date := time.Now().Format("2006-01-02")
+
+	readiness, err := getReadiness(date)
+	if err != nil {
+		fatal(err)
+	}
+	sleep, err := getDailySleep(date)
+	if err != nil {
+		fatal(err)
+	}
+
+	var parts []string
+	if readiness != nil {
+		parts = append(parts, "Readiness "+optInt(readiness.Score, ""))
+	}
+	if sleep != nil {
+		parts = append(parts, "Sleep "+optInt(sleep.Score, ""))
+	}
+	message := strings.Join(parts, " · ")
+	if message == "" {
+		message = "No data yet - your ring hasn't synced today"
+	}
+
+	sendNotification("Oura", message)
+}
+
+// sendNotification shows a desktop notification. A missing notifier is
+// not an error: login scripts shouldn't fail over it.
+func sendNotification(title, message string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", message, title)
+		cmd = exec.Command("osascript", "-e", script)
+	case "linux":
+		cmd = exec.Command("notify-send", title, message)
+	case "windows":
+		cmd = exec.Command("msg", "*", title+": "+message)
+	}
+	if cmd != nil {
+		cmd.Run()
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// onlyField is a value a --*-only flag prints bare, for one command.
+type onlyField struct {
+	flag    string
+	command string
+	value   func(date string) (*int, error)
+}
+
+var onlyFields = []onlyField{
+	{"score-only", "sleep", onlyDaily("/daily_sleep", func(d DailySleepRecord) (string, *int) { return d.Day, d.Score })},
+	{"score-only", "activity", onlyDaily("/daily_activity", func(a ActivityRecord) (string, *int) { return a.Day, a.Score })},
+	{"score-only", "readiness", onlyDaily("/daily_readiness", func(r ReadinessRecord) (string, *int) { return r.Day, r.Score })},
+	{"calories-only", "activity", onlyDaily("/daily_activity", func(a ActivityRecord) (string, *int) { return a.Day, &a.ActiveCalories })},
+	{"steps-only", "activity", onlyDaily("/daily_activity", func(a ActivityRecord) (string, *int) { return a.Day, &a.Steps })},
+}
+
+// onlyDaily returns a lookup of one value from the day's record of a
+// daily endpoint; nil if the day has no record or the value is absent.
+func onlyDaily[T any](endpoint string, pick func(T) (string, *int)) func(string) (*int, error) {
+	return func(date string) (*int, error) {
+		var resp struct {
+			Data []T `json:"data"`
+		}
+		if err := getRange(endpoint, date, date, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Data {
+			if day, v := pick(r); day == date {
+				return v, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// singleValueFlag returns the --*-only flag given, or "" if none. More than one
+// is an error.
+func singleValueFlag() string {
+	var given []string
+	for _, f := range []struct {
+		name string
+		set  *bool
+	}{
+		{"score-only", scoreOnlyFlag},
+		{"calories-only", caloriesOnlyFlag},
+		{"steps-only", stepsOnlyFlag},
+	} {
+		if *f.set {
+			given = append(given, "--"+f.name)
+		}
+	}
+	if len(given) > 1 {
+		fmt.Fprintf(os.Stderr, "%s can't be combined\n", strings.Join(given, " and "))
+		os.Exit(1)
+	}
+	if len(given) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(given[0], "--")
+}
+
+// printOnly prints the value flag selects for cmd on the date argument,
+// with nothing around it, for shell substitution. A day without the
+// value exits 1, printing nothing to stdout.
+func printOnly(cmd, flag string) {
+	var commands []string
+	for _, f := range onlyFields {
+		if f.flag != flag {
+			continue
+		}
+		if f.command == cmd {
+			date := getDateArg()
+			v, err := f.value(date)
+			if err != nil {
+				fatal(err)
+			}
+			if v == nil {
+				fmt.Fprintf(os.Stderr, "no data for %s\n", date)
+				os.Exit(1)
+			}
+			fmt.Println(*v)
+			return
+		}
+		commands = append(commands, f.command)
+	}
+	fmt.Fprintf(os.Stderr, "--%s works only with %s\n", flag, strings.Join(commands, ", "))
+	os.Exit(1)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func listFlag(name, usage string) *stringList {
+	l := &stringList{}
+	flags.Var(l, name, usage)
+	return l
+}
+
+// doRaw GETs any usercollection endpoint and pretty-prints the JSON, for
+// endpoints the CLI doesn't model yet.
+func doRaw() {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: oura raw /ENDPOINT [--from DATE --to DATE] [--param key=value ...]")
+		os.Exit(1)
+	}
+	endpoint := args[0]
+	// The path is appended to the API base, so keep it there: no scheme,
+	// host, query string or parent directories.
+	if !strings.HasPrefix(endpoint, "/") || strings.HasPrefix(endpoint, "//") ||
+		strings.ContainsAny(endpoint, "?#:\\") || strings.Contains(endpoint, "..") {
+		fmt.Fprintf(os.Stderr, "invalid endpoint %q (want a path like /daily_readiness)\n", endpoint)
+		os.Exit(1)
+	}
+
+	params := url.Values{}
+	if rangeRequested() {
+		from, to, err := parseRangeFlags()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		params = rangeParams(from, to)
+	}
+	for _, p := range *paramFlag {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok || key == "" {
+			fmt.Fprintf(os.Stderr, "invalid --param %q (want key=value)\n", p)
+			os.Exit(1)
+		}
+		params.Add(key, value)
+	}
+
+	body, err := client.Get(ctx, endpoint, params)
+	if err != nil {
+		fatal(err)
+	}
+	if *redactFlag {
+		body = redactJSON(body)
+	}
+	if *anonymizeFlag {
+		base := params.Get("start_date")
+		if base == "" {
+			base, _, _ = strings.Cut(params.Get("start_datetime"), "T")
+		}
+		if base == "" {
+			base = time.Now().Format("2006-01-02")
+		}
+		body = anonymizeJSON(body, base)
+	}
+	var out bytes.Buffer
+	if *jsonCompactFlag {
+		err = json.Compact(&out, body)
+	} else {
+		err = json.Indent(&out, body, "", "  ")
+	}
+	if err != nil {
+		// Not JSON; show it as is.
+		os.Stdout.Write(body)
+		return
+	}
+	out.WriteTo(os.Stdout)
+	fmt.Println()
+}
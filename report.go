@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// lowerIsBetter marks metrics whose best day is their lowest.
+var lowerIsBetter = map[string]bool{"resting_heart_rate": true}
+
+// doReport prints a Markdown digest of a range for a journal or email:
+// each metric's average, change against the period just before, best
+// and worst day, and a sparkline.
+func doReport() {
+	if flagGiven("format") && *formatFlag != "md" {
+		fmt.Fprintf(os.Stderr, "unknown --format %q for report (want md)\n", *formatFlag)
+		os.Exit(1)
+	}
+	if !rangeRequested() {
+		fmt.Fprintln(os.Stderr, "usage: oura report (--from YYYY-MM-DD [--to YYYY-MM-DD] | --since 7d) [--format md]")
+		os.Exit(1)
+	}
+	from, to, err := parseRangeFlags()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// The prior period has the same length and ends the day before from.
+	days := daysBetween(from, to)
+	start, _ := time.Parse("2006-01-02", from)
+	priorFrom := start.AddDate(0, 0, -len(days)).Format("2006-01-02")
+	priorDays := daysBetween(priorFrom, start.AddDate(0, 0, -1).Format("2006-01-02"))
+
+	s, err := loadSeries(priorFrom, to)
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("# Oura report: %s to %s\n\n", from, to)
+	fmt.Printf("Compared with the previous %s (%s to %s).\n\n", plural(len(days), "day"), priorDays[0], priorDays[len(priorDays)-1])
+	fmt.Println("| Metric | Average | Change | Best | Worst | Trend |")
+	fmt.Println("|--------|--------:|-------:|------|-------|-------|")
+	for _, m := range statsMetrics {
+		value := func(day string) (float64, bool) {
+			v, ok := s[m.Key][day]
+			if ok && m.Key == "total_sleep_duration" {
+				v /= 3600
+			}
+			return v, ok
+		}
+
+		better := func(a, b float64) bool {
+			if lowerIsBetter[m.Key] {
+				return a < b
+			}
+			return a > b
+		}
+
+		var current, prior []float64
+		var points []*float64
+		best, worst := "", ""
+		for _, day := range days {
+			v, ok := value(day)
+			if !ok {
+				points = append(points, nil)
+				continue
+			}
+			current = append(current, v)
+			points = append(points, &v)
+			if b, _ := value(best); best == "" || better(v, b) {
+				best = day
+			}
+			if w, _ := value(worst); worst == "" || better(w, v) {
+				worst = day
+			}
+		}
+		for _, day := range priorDays {
+			if v, ok := value(day); ok {
+				prior = append(prior, v)
+			}
+		}
+		if len(current) == 0 {
+			fmt.Printf("| %s | %s | | | | |\n", m.Label, sym.Dash)
+			continue
+		}
+
+		mean := describe(current).Mean
+		change := sym.Dash
+		if len(prior) > 0 {
+			change = formatSigned(mean-describe(prior).Mean, 1)
+		}
+		// Daily values are whole numbers except hours of sleep.
+		decimals := 0
+		if m.Key == "total_sleep_duration" {
+			decimals = 1
+		}
+		bestV, _ := value(best)
+		worstV, _ := value(worst)
+		line, _, _ := sparkline(points, len(points))
+		fmt.Printf("| %s | %s | %s | %s (%s) | %s (%s) | `%s` |\n",
+			m.Label, formatFloat(mean, 1), change,
+			formatFloat(bestV, decimals), best, formatFloat(worstV, decimals), worst, line)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// rangeChunkDays is how many days each concurrent range request covers.
+const rangeChunkDays = 7
+
+// sleepChunk is one fetched chunk of a sleep range.
+type sleepChunk struct {
+	index   int
+	days    []string
+	daily   map[string]*DailySleepRecord
+	periods map[string][]SleepRecord
+	err     error
+}
+
+// fetchSleepRange prints sleep for every day of --from/--to. Chunks are
+// fetched by up to --concurrency workers, and each chunk is printed as
+// soon as it and every earlier one have arrived, so output streams in
+// date order.
+func fetchSleepRange() {
+	from, to, err := parseRangeFlags()
+	if err != nil {
+		fatal(err)
+	}
+	chunks := chunkRange(from, to, rangeChunkDays)
+
+	// Settle the token first, so the workers don't all refresh it.
+	if _, err := client.AccessToken(ctx); err != nil {
+		fatal(err)
+	}
+
+	jobs := make(chan int)
+	results := make(chan sleepChunk)
+	go func() {
+		for i := range chunks {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+	for range min(max(*concurrencyFlag, 1), len(chunks)) {
+		go func() {
+			for i := range jobs {
+				results <- fetchSleepChunk(i, chunks[i][0], chunks[i][1])
+			}
+		}()
+	}
+
+	// Hold chunks that arrive early until the ones before them print.
+	pending := map[int]sleepChunk{}
+	printed := 0
+	for next := 0; next < len(chunks); {
+		r := <-results
+		pending[r.index] = r
+		for ; pending[next].days != nil; next++ {
+			c := pending[next]
+			delete(pending, next)
+			if c.err != nil {
+				fatal(c.err)
+			}
+			for _, day := range c.days {
+				if printed > 0 {
+					fmt.Println()
+				}
+				printSleep(day, c.daily[day], c.periods[day])
+				printed++
+			}
+		}
+	}
+}
+
+// fetchSleepChunk fetches daily sleep and sleep periods for from..to,
+// widened by a day each side as dayWindow does, and sorts them by day.
+func fetchSleepChunk(index int, from, to string) sleepChunk {
+	c := sleepChunk{
+		index:   index,
+		days:    daysBetween(from, to),
+		daily:   map[string]*DailySleepRecord{},
+		periods: map[string][]SleepRecord{},
+	}
+	start, _ := time.Parse("2006-01-02", from)
+	end, _ := time.Parse("2006-01-02", to)
+	wideFrom := start.AddDate(0, 0, -1).Format("2006-01-02")
+	wideTo := end.AddDate(0, 0, 1).Format("2006-01-02")
+
+	// As with a single day, a missing daily score isn't an error.
+	var daily DailySleepResponse
+	if getRange("/daily_sleep", wideFrom, wideTo, &daily) == nil {
+		for i := range daily.Data {
+			c.daily[daily.Data[i].Day] = &daily.Data[i]
+		}
+	}
+
+	var sleep SleepResponse
+	if err := getRange("/sleep", wideFrom, wideTo, &sleep); err != nil {
+		c.err = err
+		return c
+	}
+	for _, p := range sleep.Data {
+		if countsSleep(p) {
+			c.periods[p.Day] = append(c.periods[p.Day], p)
+		}
+	}
+	for _, records := range c.periods {
+		sortByTime(records, func(s SleepRecord) string { return s.BedtimeStart })
+	}
+	slices.Sort(c.days)
+	return c
+}
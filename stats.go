@@ -0,0 +1,483 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// statsMetric is a metric accepted by stats and its key in a series.
+type statsMetric struct {
+	Name  string
+	Key   string
+	Label string
+}
+
+var statsMetrics = []statsMetric{
+	{"readiness", "readiness_score", "Readiness score"},
+	{"sleep", "sleep_score", "Sleep score"},
+	{"activity", "activity_score", "Activity score"},
+	{"rhr", "resting_heart_rate", "Resting heart rate (bpm)"},
+	{"hrv", "average_hrv", "Average HRV (ms)"},
+	{"sleep-duration", "total_sleep_duration", "Total sleep (hours)"},
+	{"steps", "steps", "Steps"},
+	{"calories", "active_calories", "Active calories"},
+}
+
+// distribution summarizes a set of values.
+type distribution struct {
+	Count        int
+	Mean, Median float64
+	StdDev       float64
+	Min, Max     float64
+}
+
+// describe computes the distribution of values, which must be non-empty.
+// StdDev is the sample standard deviation (0 for a single value).
+func describe(values []float64) distribution {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	d := distribution{Count: len(sorted), Min: sorted[0], Max: sorted[len(sorted)-1]}
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	d.Mean = sum / float64(d.Count)
+
+	if mid := d.Count / 2; d.Count%2 == 1 {
+		d.Median = sorted[mid]
+	} else {
+		d.Median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	if d.Count > 1 {
+		var sq float64
+		for _, v := range sorted {
+			sq += (v - d.Mean) * (v - d.Mean)
+		}
+		d.StdDev = math.Sqrt(sq / float64(d.Count-1))
+	}
+	return d
+}
+
+func doStats() {
+	var names []string
+	for _, m := range statsMetrics {
+		names = append(names, m.Name)
+	}
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "usage: oura stats METRIC --from YYYY-MM-DD [--to YYYY-MM-DD]\nmetrics: %s\n", strings.Join(names, ", "))
+		os.Exit(1)
+	}
+	idx := slices.IndexFunc(statsMetrics, func(m statsMetric) bool { return m.Name == args[0] })
+	if idx < 0 {
+		fmt.Fprintf(os.Stderr, "unknown metric %q (want one of: %s)\n", args[0], strings.Join(names, ", "))
+		os.Exit(1)
+	}
+	metric := statsMetrics[idx]
+
+	from, to, err := parseRangeFlags()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	s, err := loadSeries(from, to)
+	if err != nil {
+		fatal(err)
+	}
+
+	// Missing days have no entry in the series, so they are excluded.
+	var values []float64
+	var byWeekday [7][]float64
+	for _, day := range daysBetween(from, to) {
+		if v, ok := s[metric.Key][day]; ok {
+			if metric.Key == "total_sleep_duration" {
+				v /= 3600
+			}
+			values = append(values, v)
+			t, _ := time.Parse("2006-01-02", day)
+			byWeekday[t.Weekday()] = append(byWeekday[t.Weekday()], v)
+		}
+	}
+
+	scope := ""
+	if metric.Key == "total_sleep_duration" {
+		scope = sleepScope()
+	}
+	fmt.Printf("%s%s - %s to %s%s\n", sym.Stats, metric.Label, from, to, scope)
+	fmt.Println(rule(40))
+	if len(values) == 0 {
+		fmt.Println("No data in range")
+		return
+	}
+	if *byWeekdayFlag {
+		printByWeekday(byWeekday)
+		return
+	}
+	d := describe(values)
+	fmt.Printf("Days:     %d of %d\n", d.Count, len(daysBetween(from, to)))
+	fmt.Printf("Mean:     %s\n", formatFloat(d.Mean, 1))
+	fmt.Printf("Median:   %s\n", formatFloat(d.Median, 1))
+	fmt.Printf("Std Dev:  %s\n", formatFloat(d.StdDev, 1))
+	fmt.Printf("Min:      %s\n", formatFloat(d.Min, 1))
+	fmt.Printf("Max:      %s\n", formatFloat(d.Max, 1))
+}
+
+// printByWeekday prints the mean per day of the week, Monday first, with
+// the number of days behind each mean.
+func printByWeekday(byWeekday [7][]float64) {
+	fmt.Printf("%-10s %6s  %s\n", "Weekday", "Mean", "Days")
+	for i := 1; i <= 7; i++ {
+		wd := time.Weekday(i % 7)
+		if len(byWeekday[wd]) == 0 {
+			fmt.Printf("%-10s %6s  %d\n", wd, "-", 0)
+			continue
+		}
+		fmt.Printf("%-10s %6.1f  %d\n", wd, describe(byWeekday[wd]).Mean, len(byWeekday[wd]))
+	}
+}
+
+// fetchSleepRegularity reports how consistent main-sleep bedtimes were
+// over --from/--to, as the standard deviation of the bedtime clock time.
+func fetchSleepRegularity() {
+	from, to, err := parseRangeFlags()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var data SleepResponse
+	if err := getRange("/sleep", from, to, &data); err != nil {
+		fatal(err)
+	}
+
+	var bedtimes []float64
+	for _, p := range data.Data {
+		if p.Type != "long_sleep" || p.Day < from || p.Day > to {
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, p.BedtimeStart)
+		if err != nil {
+			continue
+		}
+		bedtimes = append(bedtimes, bedtimeMinutes(start.Local()))
+	}
+
+	fmt.Printf("%sSleep Regularity - %s to %s\n", sym.Sleep, from, to)
+	fmt.Println(rule(40))
+	if len(bedtimes) == 0 {
+		fmt.Println("No main sleep in range")
+		return
+	}
+	d := describe(bedtimes)
+	fmt.Printf("Nights:       %d\n", d.Count)
+	fmt.Printf("Avg Bedtime:  %s\n", formatClock(d.Mean))
+	fmt.Printf("Earliest:     %s\n", formatClock(d.Min))
+	fmt.Printf("Latest:       %s\n", formatClock(d.Max))
+	fmt.Printf("Spread:       %s%s (std dev)\n", sym.PlusMinus, formatDuration(int(d.StdDev*60)))
+}
+
+// bedtimeMinutes returns minutes since the evening's midnight. Bedtimes
+// before noon count as after midnight (e.g. 00:30 is 1470), so a night
+// at 23:50 and one at 00:10 are 20 minutes apart rather than 23 hours.
+func bedtimeMinutes(t time.Time) float64 {
+	m := float64(t.Hour()*60 + t.Minute())
+	if t.Hour() < 12 {
+		m += 24 * 60
+	}
+	return m
+}
+
+// formatClock formats minutes since midnight (possibly past 24h) as a
+// clock time.
+func formatClock(minutes float64) string {
+	m := int(math.Round(minutes)) % (24 * 60)
+	return time.Date(0, 1, 1, m/60, m%60, 0, 0, time.UTC).Format(clockLayout())
+}
+
+// baselineMetrics are the scores today/all compare with --baseline.
+var baselineMetrics = []statsMetric{
+	{"readiness", "readiness_score", "Readiness"},
+	{"sleep", "sleep_score", "Sleep"},
+	{"activity", "activity_score", "Activity"},
+}
+
+// printBaseline compares date's scores with their mean over the window
+// (e.g. 30d) of days before it. The date itself is not in the baseline.
+func printBaseline(date, window string) {
+	days, err := parseLookback(window)
+	if err != nil || days == 0 {
+		fmt.Fprintf(os.Stderr, "invalid --baseline %q (want e.g. 30d or 4w)\n", window)
+		os.Exit(1)
+	}
+	end, _ := time.Parse("2006-01-02", date)
+	from := end.AddDate(0, 0, -days).Format("2006-01-02")
+	last := end.AddDate(0, 0, -1).Format("2006-01-02")
+
+	s, err := loadSeries(from, date)
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("%svs %d-day baseline\n", sym.Stats, days)
+	fmt.Println(rule(40))
+	for _, m := range baselineMetrics {
+		today, ok := s[m.Key][date]
+		if !ok {
+			fmt.Printf("%-11s no data\n", m.Label)
+			continue
+		}
+		var window []float64
+		for _, day := range daysBetween(from, last) {
+			if v, ok := s[m.Key][day]; ok {
+				window = append(window, v)
+			}
+		}
+		if len(window) == 0 {
+			fmt.Printf("%-11s %3.0f  no baseline\n", m.Label, today)
+			continue
+		}
+		mean := describe(window).Mean
+		delta := formatSigned(today-mean, 1)
+		tag := "at baseline"
+		switch {
+		case strings.HasPrefix(delta, "+"):
+			tag = "above baseline"
+		case strings.HasPrefix(delta, "-"):
+			tag = "below baseline"
+		}
+		fmt.Printf("%-11s %3.0f  avg %s  %6s  %s\n", m.Label, today, formatFloat(mean, 1), delta, tag)
+	}
+}
+
+// streakWindow is how many days activity --streak looks back.
+const streakWindow = 90
+
+// fetchActivityStreak prints the current and longest run of consecutive
+// days meeting the step goal, or the activity score in "streak_score"
+// when that is set. Today counts once met but doesn't break the streak
+// before then, since the day isn't over.
+func fetchActivityStreak() {
+	today := time.Now()
+	to := today.Format("2006-01-02")
+	from := today.AddDate(0, 0, -(streakWindow - 1)).Format("2006-01-02")
+
+	var data ActivityResponse
+	if err := getRange("/daily_activity", from, to, &data); err != nil {
+		fatal(err)
+	}
+
+	goal := fmt.Sprintf("%d steps", stepGoal())
+	met := func(a ActivityRecord) bool { return a.Steps >= stepGoal() }
+	if config.StreakScore > 0 {
+		goal = fmt.Sprintf("activity score %d+", config.StreakScore)
+		met = func(a ActivityRecord) bool { return a.Score != nil && *a.Score >= config.StreakScore }
+	}
+	metOn := map[string]bool{}
+	for _, a := range data.Data {
+		metOn[a.Day] = met(a)
+	}
+
+	days := daysBetween(from, to)
+	if !metOn[to] {
+		days = days[:len(days)-1]
+	}
+
+	var current, longest, run int
+	var longestEnd string
+	for _, day := range days {
+		if !metOn[day] {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest, longestEnd = run, day
+		}
+	}
+	current = run
+
+	fmt.Printf("%sActivity Streak - %s\n", sym.Activity, goal)
+	fmt.Println(rule(40))
+	if current > 0 {
+		fmt.Printf("Current:  %s (since %s)\n", plural(current, "day"), days[len(days)-current])
+	} else {
+		fmt.Println("Current:  0 days")
+	}
+	if longest > 0 {
+		end, _ := time.Parse("2006-01-02", longestEnd)
+		start := end.AddDate(0, 0, -(longest - 1)).Format("2006-01-02")
+		fmt.Printf("Longest:  %s (%s to %s)\n", plural(longest, "day"), start, longestEnd)
+	} else {
+		fmt.Println("Longest:  0 days")
+	}
+	fmt.Printf("Window:   last %d days\n", streakWindow)
+}
+
+// plural formats n with a noun, adding "s" unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// defaultTrendDays is the range rhr shows without --from or --since.
+const defaultTrendDays = 30
+
+// fetchRHRTrend lists the main sleep's lowest heart rate, in bpm, for
+// each day of a range, with a sparkline and the direction it is moving
+// in. Readiness's resting_heart_rate is a 0-100 contributor score rather
+// than a rate, so the sleep value stands in for resting heart rate.
+func fetchRHRTrend() {
+	to := time.Now().Format("2006-01-02")
+	from := time.Now().AddDate(0, 0, -(defaultTrendDays - 1)).Format("2006-01-02")
+	if rangeRequested() {
+		var err error
+		if from, to, err = parseRangeFlags(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	var data SleepResponse
+	if err := getRange("/sleep", from, to, &data); err != nil {
+		fatal(err)
+	}
+	rhr := map[string]float64{}
+	for _, p := range data.Data {
+		if p.Type == "long_sleep" && p.LowestHeartRate != nil && *p.LowestHeartRate > 0 {
+			rhr[p.Day] = float64(*p.LowestHeartRate)
+		}
+	}
+
+	fmt.Printf("%sLowest Sleeping HR - %s to %s\n", sym.HeartRate, from, to)
+	fmt.Println(rule(40))
+	var values []*float64
+	for _, day := range daysBetween(from, to) {
+		v, ok := rhr[day]
+		if !ok {
+			fmt.Printf("%s  %s\n", day, sym.Dash)
+			values = append(values, nil)
+			continue
+		}
+		fmt.Printf("%s  %.0f bpm\n", day, v)
+		values = append(values, &v)
+	}
+	if len(rhr) == 0 {
+		fmt.Println("No lowest sleeping heart rate in range")
+		return
+	}
+
+	line, lo, hi := sparkline(values, barWidth(60, 15))
+	fmt.Println()
+	fmt.Printf("Trend:      %s (%.0f-%.0f bpm)\n", line, lo, hi)
+	if len(rhr) >= 2 {
+		slope := trendSlope(values) * 7
+		fmt.Printf("Direction:  %s (%s bpm/week)\n", trendDirection(slope, 0.5), formatSigned(slope, 1))
+	}
+}
+
+// nightsLookback is how far before the requested nights sleep --nights
+// looks for the most recent night with data.
+const nightsLookback = 7
+
+// fetchNights prints a table of the last n nights' sleep score and main
+// sleep total, ending with the most recent night that has data, and the
+// direction the score is moving in.
+func fetchNights(n int) {
+	today := time.Now()
+	to := today.Format("2006-01-02")
+	from := today.AddDate(0, 0, -(n - 1 + nightsLookback)).Format("2006-01-02")
+
+	var daily DailySleepResponse
+	if err := getRange("/daily_sleep", from, to, &daily); err != nil {
+		fatal(err)
+	}
+	var sleep SleepResponse
+	if err := getRange("/sleep", from, to, &sleep); err != nil {
+		fatal(err)
+	}
+	scores := map[string]*int{}
+	for _, d := range daily.Data {
+		scores[d.Day] = d.Score
+	}
+	totals := map[string]int{}
+	last := ""
+	for _, p := range sleep.Data {
+		if p.Type != "long_sleep" || p.Day < from || p.Day > to {
+			continue
+		}
+		totals[p.Day] += p.TotalSleepDuration
+		last = max(last, p.Day)
+	}
+	if last == "" {
+		fmt.Println("No main sleep in the last", plural(n+nightsLookback, "day"))
+		return
+	}
+
+	end, _ := time.Parse("2006-01-02", last)
+	days := daysBetween(end.AddDate(0, 0, -(n-1)).Format("2006-01-02"), last)
+
+	fmt.Printf("%sLast %s\n", sym.Sleep, plural(n, "Night"))
+	fmt.Println(rule(40))
+	fmt.Println("Night       Score  Total")
+	var values []*float64
+	for _, day := range days {
+		total := sym.Dash
+		if t, ok := totals[day]; ok {
+			total = formatDuration(t)
+		}
+		var v *float64
+		if score := scores[day]; score != nil {
+			f := float64(*score)
+			v = &f
+		}
+		values = append(values, v)
+		fmt.Printf("%s  %-5s  %s\n", day, optInt(scores[day], ""), total)
+	}
+
+	if slices.ContainsFunc(values, func(v *float64) bool { return v != nil }) {
+		slope := trendSlope(values)
+		fmt.Println()
+		fmt.Printf("Direction:  %s (%s points/night)\n", trendDirection(slope, 0.5), formatSigned(slope, 1))
+	}
+}
+
+// trendSlope is the least-squares slope of values per step, skipping nil
+// values. It needs at least two values.
+func trendSlope(values []*float64) float64 {
+	var n, sx, sy, sxx, sxy float64
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		x := float64(i)
+		n++
+		sx += x
+		sy += *v
+		sxx += x * x
+		sxy += x * *v
+	}
+	if d := n*sxx - sx*sx; d != 0 {
+		return (n*sxy - sx*sy) / d
+	}
+	return 0
+}
+
+// trendDirection describes a slope, treating changes smaller than
+// threshold as steady.
+func trendDirection(slope, threshold float64) string {
+	switch {
+	case slope >= threshold:
+		return "rising " + sym.Up
+	case slope <= -threshold:
+		return "falling " + sym.Down
+	}
+	return "steady"
+}
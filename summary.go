@@ -0,0 +1,422 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// summarySchemaVersion is DaySummary's schema_version. Bump it when a
+// field is renamed, removed or changes type; adding fields doesn't.
+const summarySchemaVersion = 1
+
+// DaySummary is the normalized view of one day, used for summary's JSON
+// output and --template. Its field names and types are a stable contract
+// for scripts; --schema prints them as a JSON Schema. Pointer fields are
+// null when there is no data.
+type DaySummary struct {
+	SchemaVersion int    `json:"schema_version"`
+	Day           string `json:"day"` // YYYY-MM-DD
+
+	ReadinessScore     *int `json:"readiness_score"`      // 0-100
+	SleepScore         *int `json:"sleep_score"`          // 0-100
+	ActivityScore      *int `json:"activity_score"`       // 0-100
+	RestingHeartRate   *int `json:"resting_heart_rate"`   // bpm, lowest HR of the main sleep
+	AverageHRV         *int `json:"average_hrv"`          // ms, over the main sleep
+	TotalSleepDuration *int `json:"total_sleep_duration"` // seconds, all sleep periods
+	Steps              *int `json:"steps"`
+	ActiveCalories     *int `json:"active_calories"` // kcal
+
+	// Set only with --rolling.
+	*RollingAverages
+}
+
+// RollingAverages are trailing means ending on the summary's day, rounded
+// to one decimal. Days without data are ignored; a window with no data at
+// all is null.
+type RollingAverages struct {
+	ReadinessScore7d    *float64 `json:"readiness_score_7d_avg"`
+	ReadinessScore30d   *float64 `json:"readiness_score_30d_avg"`
+	SleepScore7d        *float64 `json:"sleep_score_7d_avg"`
+	SleepScore30d       *float64 `json:"sleep_score_30d_avg"`
+	ActivityScore7d     *float64 `json:"activity_score_7d_avg"`
+	ActivityScore30d    *float64 `json:"activity_score_30d_avg"`
+	RestingHeartRate7d  *float64 `json:"resting_heart_rate_7d_avg"`
+	RestingHeartRate30d *float64 `json:"resting_heart_rate_30d_avg"`
+}
+
+// PeriodSummary is summary's output with --group-by: a week or month of
+// days, with the mean of each daily value and totals for steps and
+// calories. Days without data are ignored; a metric with no data in the
+// period is null.
+type PeriodSummary struct {
+	SchemaVersion int    `json:"schema_version"`
+	Period        string `json:"period"` // 2024-W03 (ISO week) or 2024-01
+	From          string `json:"from"`   // first day of the period in the range
+	To            string `json:"to"`     // last day of the period in the range
+	Days          int    `json:"days"`
+
+	ReadinessScore     *float64 `json:"readiness_score_avg"`
+	SleepScore         *float64 `json:"sleep_score_avg"`
+	ActivityScore      *float64 `json:"activity_score_avg"`
+	RestingHeartRate   *float64 `json:"resting_heart_rate_avg"`
+	AverageHRV         *float64 `json:"average_hrv_avg"`
+	TotalSleepDuration *float64 `json:"total_sleep_duration_avg"`
+	Steps              *float64 `json:"steps_avg"`
+	ActiveCalories     *float64 `json:"active_calories_avg"`
+
+	StepsTotal          *int `json:"steps_total"`
+	ActiveCaloriesTotal *int `json:"active_calories_total"`
+}
+
+// periodOf returns the --group-by bucket of day: its ISO week
+// (2024-W03) or its month (2024-01).
+func periodOf(day, by string) string {
+	t, _ := time.Parse("2006-01-02", day)
+	if by == "month" {
+		return t.Format("2006-01")
+	}
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// groupSummaries buckets days, which are in order, into periods.
+func groupSummaries(s series, days []string, by string) []PeriodSummary {
+	var periods []PeriodSummary
+	var bucket []string
+	flush := func() {
+		if len(bucket) == 0 {
+			return
+		}
+		periods = append(periods, PeriodSummary{
+			SchemaVersion:       summarySchemaVersion,
+			Period:              periodOf(bucket[0], by),
+			From:                bucket[0],
+			To:                  bucket[len(bucket)-1],
+			Days:                len(bucket),
+			ReadinessScore:      s.mean("readiness_score", bucket),
+			SleepScore:          s.mean("sleep_score", bucket),
+			ActivityScore:       s.mean("activity_score", bucket),
+			RestingHeartRate:    s.mean("resting_heart_rate", bucket),
+			AverageHRV:          s.mean("average_hrv", bucket),
+			TotalSleepDuration:  s.mean("total_sleep_duration", bucket),
+			Steps:               s.mean("steps", bucket),
+			ActiveCalories:      s.mean("active_calories", bucket),
+			StepsTotal:          s.total("steps", bucket),
+			ActiveCaloriesTotal: s.total("active_calories", bucket),
+		})
+		bucket = nil
+	}
+	for _, day := range days {
+		if len(bucket) > 0 && periodOf(day, by) != periodOf(bucket[0], by) {
+			flush()
+		}
+		bucket = append(bucket, day)
+	}
+	flush()
+	return periods
+}
+
+// summarySchema returns the JSON Schema of DaySummary, derived from its
+// fields so the two can't drift apart.
+func summarySchema() map[string]any {
+	properties := map[string]any{}
+	var required []string
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous {
+				// Embedded rolling averages appear only with --rolling.
+				walk(f.Type.Elem())
+				continue
+			}
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			properties[name] = map[string]any{"type": schemaType(f.Type)}
+			if t == reflect.TypeOf(DaySummary{}) {
+				required = append(required, name)
+			}
+		}
+	}
+	walk(reflect.TypeOf(DaySummary{}))
+	properties["schema_version"] = map[string]any{"type": "integer", "const": summarySchemaVersion}
+	return map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      "DaySummary",
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// schemaType maps a field's Go type to JSON Schema types; pointers are
+// nullable.
+func schemaType(t reflect.Type) any {
+	if t.Kind() == reflect.Pointer {
+		return []string{schemaType(t.Elem()).(string), "null"}
+	}
+	switch t.Kind() {
+	case reflect.Int:
+		return "integer"
+	case reflect.Float64:
+		return "number"
+	}
+	return "string"
+}
+
+// longestRollingWindow is how many days --rolling needs to fetch.
+const longestRollingWindow = 30
+
+// series maps metric → day → value. Missing days have no entry.
+type series map[string]map[string]float64
+
+func (s series) set(metric, day string, v float64) {
+	if s[metric] == nil {
+		s[metric] = map[string]float64{}
+	}
+	s[metric][day] = v
+}
+
+// setOpt sets a value the API may omit; an omitted one stays missing
+// rather than becoming 0.
+func (s series) setOpt(metric, day string, v *int) {
+	if v != nil {
+		s.set(metric, day, float64(*v))
+	}
+}
+
+// loadSeries fetches the daily metrics for the inclusive range.
+func loadSeries(from, to string) (series, error) {
+	s := series{}
+	inRange := func(day string) bool { return day >= from && day <= to }
+
+	var readiness ReadinessResponse
+	if err := getRange("/daily_readiness", from, to, &readiness); err != nil {
+		return nil, err
+	}
+	for _, r := range readiness.Data {
+		if inRange(r.Day) {
+			s.setOpt("readiness_score", r.Day, r.Score)
+		}
+	}
+
+	var dailySleep DailySleepResponse
+	if err := getRange("/daily_sleep", from, to, &dailySleep); err != nil {
+		return nil, err
+	}
+	for _, d := range dailySleep.Data {
+		if inRange(d.Day) {
+			s.setOpt("sleep_score", d.Day, d.Score)
+		}
+	}
+
+	var activity ActivityResponse
+	if err := getRange("/daily_activity", from, to, &activity); err != nil {
+		return nil, err
+	}
+	for _, a := range activity.Data {
+		if inRange(a.Day) {
+			s.setOpt("activity_score", a.Day, a.Score)
+			s.set("steps", a.Day, float64(a.Steps))
+			s.set("active_calories", a.Day, float64(a.ActiveCalories))
+		}
+	}
+
+	var sleep SleepResponse
+	if err := getRange("/sleep", from, to, &sleep); err != nil {
+		return nil, err
+	}
+	for _, p := range sleep.Data {
+		if !inRange(p.Day) || !countsSleep(p) {
+			continue
+		}
+		s.set("total_sleep_duration", p.Day, s["total_sleep_duration"][p.Day]+float64(p.TotalSleepDuration))
+		// The main sleep's lowest HR is the resting heart rate.
+		if p.Type == "long_sleep" {
+			s.setOpt("resting_heart_rate", p.Day, p.LowestHeartRate)
+			s.setOpt("average_hrv", p.Day, p.AverageHRV)
+		}
+	}
+
+	return s, nil
+}
+
+func (s series) value(metric, day string) *int {
+	v, ok := s[metric][day]
+	if !ok {
+		return nil
+	}
+	n := int(v)
+	return &n
+}
+
+// average returns the mean of metric over the n days ending on day.
+func (s series) average(metric, day string, n int) *float64 {
+	end, _ := time.Parse("2006-01-02", day)
+	days := make([]string, n)
+	for i := range days {
+		days[i] = end.AddDate(0, 0, -i).Format("2006-01-02")
+	}
+	return s.mean(metric, days)
+}
+
+// mean is the mean of metric over days, rounded to one decimal, or nil
+// if none of them has data.
+func (s series) mean(metric string, days []string) *float64 {
+	var sum float64
+	var count int
+	for _, day := range days {
+		if v, ok := s[metric][day]; ok {
+			sum += v
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	avg := math.Round(sum/float64(count)*10) / 10
+	return &avg
+}
+
+// total is the sum of metric over days, or nil if none has data.
+func (s series) total(metric string, days []string) *int {
+	var sum float64
+	var count int
+	for _, day := range days {
+		if v, ok := s[metric][day]; ok {
+			sum += v
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	n := int(sum)
+	return &n
+}
+
+func daySummary(s series, day string) DaySummary {
+	return DaySummary{
+		SchemaVersion:      summarySchemaVersion,
+		Day:                day,
+		ReadinessScore:     s.value("readiness_score", day),
+		SleepScore:         s.value("sleep_score", day),
+		ActivityScore:      s.value("activity_score", day),
+		RestingHeartRate:   s.value("resting_heart_rate", day),
+		AverageHRV:         s.value("average_hrv", day),
+		TotalSleepDuration: s.value("total_sleep_duration", day),
+		Steps:              s.value("steps", day),
+		ActiveCalories:     s.value("active_calories", day),
+	}
+}
+
+func rollingAverages(s series, day string) *RollingAverages {
+	return &RollingAverages{
+		ReadinessScore7d:    s.average("readiness_score", day, 7),
+		ReadinessScore30d:   s.average("readiness_score", day, 30),
+		SleepScore7d:        s.average("sleep_score", day, 7),
+		SleepScore30d:       s.average("sleep_score", day, 30),
+		ActivityScore7d:     s.average("activity_score", day, 7),
+		ActivityScore30d:    s.average("activity_score", day, 30),
+		RestingHeartRate7d:  s.average("resting_heart_rate", day, 7),
+		RestingHeartRate30d: s.average("resting_heart_rate", day, 30),
+	}
+}
+
+func fetchSummary(date string) {
+	if *schemaFlag {
+		data, _ := marshalJSON(summarySchema())
+		fmt.Println(string(data))
+		return
+	}
+	from, to := date, date
+	if rangeRequested() {
+		var err error
+		if from, to, err = parseRangeFlags(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *formatFlag != "json" && *formatFlag != "jsonl" {
+		fmt.Fprintf(os.Stderr, "unknown --format %q (want json or jsonl)\n", *formatFlag)
+		os.Exit(1)
+	}
+	switch {
+	case *groupByFlag != "" && *groupByFlag != "week" && *groupByFlag != "month":
+		fmt.Fprintf(os.Stderr, "unknown --group-by %q (want week or month)\n", *groupByFlag)
+		os.Exit(1)
+	case *groupByFlag != "" && *rollingFlag:
+		fmt.Fprintln(os.Stderr, "--group-by cannot be combined with --rolling")
+		os.Exit(1)
+	}
+
+	var tmpl *template.Template
+	if *templateFlag != "" {
+		var err error
+		if tmpl, err = template.New("summary").Parse(*templateFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --template: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	fetchFrom := from
+	if *rollingFlag {
+		start, _ := time.Parse("2006-01-02", from)
+		fetchFrom = start.AddDate(0, 0, -(longestRollingWindow - 1)).Format("2006-01-02")
+	}
+
+	s, err := loadSeries(fetchFrom, to)
+	if err != nil {
+		fatal(err)
+	}
+
+	var days []any
+	if *groupByFlag != "" {
+		for i, p := range groupSummaries(s, daysBetween(from, to), *groupByFlag) {
+			if *anonymizeFlag {
+				p.Period = fmt.Sprintf("Period %d", i+1)
+				p.From, p.To = anonDay(from, p.From), anonDay(from, p.To)
+			}
+			days = append(days, p)
+		}
+	} else {
+		for _, day := range daysBetween(from, to) {
+			out := daySummary(s, day)
+			if *rollingFlag {
+				out.RollingAverages = rollingAverages(s, day)
+			}
+			if *anonymizeFlag {
+				out.Day = anonDay(from, day)
+			}
+			days = append(days, out)
+		}
+	}
+
+	switch {
+	case tmpl != nil:
+		for _, out := range days {
+			if err := tmpl.Execute(os.Stdout, out); err != nil {
+				fmt.Fprintf(os.Stderr, "\ntemplate error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println()
+		}
+	case *formatFlag == "jsonl":
+		// One compact object per line, for jq and log pipelines.
+		enc := json.NewEncoder(os.Stdout)
+		for _, out := range days {
+			enc.Encode(out)
+		}
+	case from == to && *groupByFlag == "":
+		data, _ := marshalJSON(days[0])
+		fmt.Println(string(data))
+	default:
+		data, _ := marshalJSON(days)
+		fmt.Println(string(data))
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import "strings"
+
+// symbolSet holds every emoji and box-drawing character in the output,
+// so --ascii can swap them all for plain ASCII at startup. Icons include
+// their trailing spacing, since some emoji render wider than others.
+type symbolSet struct {
+	Sleep, Readiness, Activity, HeartRate, Stress string
+	SpO2, Resilience, Workout, Stats, Warning     string
+
+	// Sleep period types, by API type, and the fallback for unknown types.
+	SleepTypes map[string]string
+	OtherSleep string
+
+	Rule      string // horizontal rule under headers
+	BoxTop    string // banner above and below all's title
+	BoxBottom string
+	BoxSide   string
+
+	Check, Cross string
+	Arrow        string
+	Up, Down     string
+	Degree       string
+	PlusMinus    string
+	Dash         string // placeholder for no data
+
+	BarFull, BarEmpty string
+	Stages            []string // deep, light, REM, awake
+	ActivityClasses   []string // class_5_min 0 (non-wear) to 5 (high)
+	Spark             []string // sparkline levels, lowest first
+}
+
+var unicodeSymbols = symbolSet{
+	Sleep:      "🌙 ",
+	Readiness:  "💪 ",
+	Activity:   "🏃 ",
+	HeartRate:  "❤️  ",
+	Stress:     "😤 ",
+	SpO2:       "🫁 ",
+	Resilience: "🛡️  ",
+	Workout:    "🏋️  ",
+	Stats:      "📊 ",
+	Warning:    "⚠  ",
+	SleepTypes: map[string]string{
+		"long_sleep": "🛏️  Main Sleep",
+		"sleep":      "💤 Short Sleep",
+		"late_nap":   "🌆 Late Nap",
+		"rest":       "🧘 Rest",
+		"deleted":    "🗑️  Deleted",
+	},
+	OtherSleep:      "😴 ",
+	Rule:            "─",
+	BoxTop:          "╔══════════════════════════════════════╗",
+	BoxBottom:       "╚══════════════════════════════════════╝",
+	BoxSide:         "║",
+	Check:           "✓",
+	Cross:           "✗",
+	Arrow:           "→",
+	Up:              "↑",
+	Down:            "↓",
+	Degree:          "°",
+	PlusMinus:       "±",
+	Dash:            "—",
+	BarFull:         "█",
+	BarEmpty:        "░",
+	Stages:          []string{"█", "▓", "▒", "░"},
+	ActivityClasses: []string{" ", "_", "░", "▒", "▓", "█"},
+	Spark:           []string{"▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"},
+}
+
+var asciiSymbols = symbolSet{
+	Warning: "! ",
+	SleepTypes: map[string]string{
+		"long_sleep": "Main Sleep",
+		"sleep":      "Short Sleep",
+		"late_nap":   "Late Nap",
+		"rest":       "Rest",
+		"deleted":    "Deleted",
+	},
+	Rule:            "-",
+	BoxTop:          "+" + strings.Repeat("=", 38) + "+",
+	BoxBottom:       "+" + strings.Repeat("=", 38) + "+",
+	BoxSide:         "|",
+	Check:           "[ok]",
+	Cross:           "[FAIL]",
+	Arrow:           "->",
+	Up:              "^",
+	Down:            "v",
+	Degree:          "",
+	PlusMinus:       "+/-",
+	Dash:            "-",
+	BarFull:         "#",
+	BarEmpty:        ".",
+	Stages:          []string{"#", "=", "-", "."},
+	ActivityClasses: []string{" ", "_", ".", "-", "=", "#"},
+	Spark:           []string{"_", ".", "-", "=", "+", "*", "#"},
+}
+
+// sym is the symbol set in use; main switches it for --ascii.
+var sym = unicodeSymbols
+
+// rule returns a horizontal rule of width characters.
+func rule(width int) string {
+	return strings.Repeat(sym.Rule, width)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// tableCommands are the daily views that --format table applies to.
+var tableCommands = map[string]bool{
+	"today": true, "all": true, "sleep": true, "activity": true,
+	"readiness": true, "heartrate": true, "stress": true, "spo2": true,
+	"resilience": true, "vo2": true, "workout": true,
+}
+
+// tableFormat reports whether daily views should print as tables.
+func tableFormat() bool {
+	return *formatFlag == "table"
+}
+
+// printRows prints label/value rows. By default each label gets a colon
+// and is padded to width, the column its view was laid out for; with
+// --format table the rows form a two-column table as wide as its longest
+// label. A row without a label continues the value above it, and an
+// empty row is a blank line, left out of a table so it stays aligned as
+// one block.
+func printRows(width int, rows []kv) {
+	var v rowView
+	v.add(width, rows...)
+	v.print()
+}
+
+// rowView collects a view made of several blocks of rows, and lines
+// between them, so that --format table prints it as one table instead of
+// sizing each block on its own.
+type rowView struct {
+	entries []viewEntry
+}
+
+// viewEntry is a row laid out at width or, when text is set, a line
+// printed as is by default and shown as row in a table (left out if
+// row is empty).
+type viewEntry struct {
+	width int
+	row   kv
+	text  string
+}
+
+// add appends rows laid out at width by default.
+func (v *rowView) add(width int, rows ...kv) {
+	for _, r := range rows {
+		v.entries = append(v.entries, viewEntry{width: width, row: r})
+	}
+}
+
+// line appends text printed as is by default, such as a heading or a
+// chart, with row standing in for it in a table.
+func (v *rowView) line(text string, row kv) {
+	v.entries = append(v.entries, viewEntry{row: row, text: text})
+}
+
+func (v *rowView) print() {
+	if tableFormat() {
+		var buf bytes.Buffer
+		tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+		for _, e := range v.entries {
+			if e.row != (kv{}) {
+				fmt.Fprintf(tw, "%s\t%s\n", e.row.Key, e.row.Value)
+			}
+		}
+		tw.Flush()
+		// A heading has no value, but tabwriter still pads its label.
+		for line := range strings.Lines(buf.String()) {
+			fmt.Println(strings.TrimRight(line, " \n"))
+		}
+		return
+	}
+	for _, e := range v.entries {
+		switch {
+		case e.text != "":
+			fmt.Println(e.text)
+		case e.row == (kv{}):
+			fmt.Println()
+		case e.row.Key == "":
+			fmt.Printf("%*s%s\n", e.width, "", e.row.Value)
+		default:
+			fmt.Printf("%-*s%s\n", e.width, e.row.Key+":", e.row.Value)
+		}
+	}
+}
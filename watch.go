@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultWatchInterval is how often watch checks without --interval.
+const defaultWatchInterval = 15 * time.Minute
+
+// doWatch checks today's value of --metric every --interval and alerts
+// when it crosses --below or --above. Each day alerts at most once, so a
+// bad day doesn't re-notify on every check. A failed check is reported on
+// stderr and tried again at the next interval.
+func doWatch() {
+	idx := slices.IndexFunc(statsMetrics, func(m statsMetric) bool { return m.Name == *metricFlag })
+	if idx < 0 {
+		var names []string
+		for _, m := range statsMetrics {
+			names = append(names, m.Name)
+		}
+		fmt.Fprintf(os.Stderr, "usage: oura watch --metric METRIC (--below N | --above N) [--interval 15m] [--notify]\nmetrics: %s\n", strings.Join(names, ", "))
+		os.Exit(1)
+	}
+	metric := statsMetrics[idx]
+	below, above := flagGiven("below"), flagGiven("above")
+	if !below && !above {
+		fmt.Fprintln(os.Stderr, "watch needs --below or --above")
+		os.Exit(1)
+	}
+	interval := *intervalFlag
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	var alerted string // day of the last alert
+	for {
+		date := time.Now().Format("2006-01-02")
+		s, err := loadSeries(date, date)
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		now := time.Now().Format(clockLayout())
+		v, ok := s[metric.Key][date]
+		decimals := 0
+		if metric.Key == "total_sleep_duration" {
+			v /= 3600
+			decimals = 1
+		}
+		switch {
+		case err != nil:
+			// Keep watching through transient failures.
+			fmt.Fprintf(os.Stderr, "%s  %v\n", now, err)
+		case !ok:
+			fmt.Printf("%s  %s: no data yet\n", now, metric.Name)
+		case below && v < *belowFlag, above && v > *aboveFlag:
+			bound, limit := "below", *belowFlag
+			if !(below && v < *belowFlag) {
+				bound, limit = "above", *aboveFlag
+			}
+			msg := fmt.Sprintf("%s %s is %s %s", metric.Label, formatFloat(v, decimals), bound, formatFloat(limit, decimals))
+			fmt.Printf("%s  %s%s\n", now, sym.Warning, msg)
+			if *notifyFlag && alerted != date {
+				sendNotification("Oura", msg)
+			}
+			alerted = date
+		default:
+			fmt.Printf("%s  %s: %s\n", now, metric.Name, formatFloat(v, decimals))
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// flagGiven reports whether a flag was set on the command line, for
+// flags whose zero value is meaningful.
+func flagGiven(name string) bool {
+	given := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
+}
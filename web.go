@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// webPages maps the pages accepted by web to their Oura web app URL.
+var webPages = map[string]string{
+	"dashboard": "https://cloud.ouraring.com/dashboard",
+	"sleep":     "https://cloud.ouraring.com/sleep",
+	"activity":  "https://cloud.ouraring.com/activity",
+	"readiness": "https://cloud.ouraring.com/readiness",
+	"trends":    "https://cloud.ouraring.com/trends",
+}
+
+// doWeb opens an Oura web app page in the browser. The browser session
+// handles login, so no token is needed.
+func doWeb() {
+	page := "dashboard"
+	if len(args) > 0 {
+		page = args[0]
+	}
+	url, ok := webPages[page]
+	if !ok {
+		var names []string
+		for name := range webPages {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		fmt.Fprintf(os.Stderr, "unknown page %q (want one of: %s)\n", page, strings.Join(names, ", "))
+		os.Exit(1)
+	}
+	fmt.Println(url)
+	openBrowser(url)
+}